@@ -0,0 +1,93 @@
+package core
+
+import (
+	"sync"
+	"time"
+)
+
+// The time an experiment may take, shared by its workers
+// A nil budget is never exhausted
+type Budget struct {
+	// The start of the experiment
+	start time.Time
+	// The wall-clock time of the experiment (0 for no limit)
+	duration time.Duration
+	// The total evaluation time of all the workers (0 for no limit)
+	cost time.Duration
+	// The number of workers sharing the budget
+	workers int
+	// The evaluation time spent so far and the number of evaluations
+	spent       time.Duration
+	evaluations int
+	mutex       *sync.Mutex
+}
+
+// Creates the budget of an experiment that starts now
+// duration limits the wall-clock time and cost the sum of the evaluation times of the workers (0 for no limit)
+func NewBudget(duration, cost time.Duration, workers int) *Budget {
+	return &Budget{
+		start:    time.Now(),
+		duration: duration,
+		cost:     cost,
+		workers:  workers,
+		mutex:    &sync.Mutex{},
+	}
+}
+
+// The budget in vargs["budget"], nil if there is none
+func budgetOf(vargs map[string]interface{}) *Budget {
+	budget, _ := vargs["budget"].(*Budget)
+	return budget
+}
+
+// Checks if the deadline has passed or the evaluations cost more than allowed
+func (b *Budget) Exhausted() bool {
+	if b == nil {
+		return false
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.duration > 0 && time.Since(b.start) >= b.duration || b.cost > 0 && b.spent >= b.cost
+}
+
+// Records an evaluation that took the given time
+func (b *Budget) Spend(cost time.Duration) {
+	if b == nil {
+		return
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.spent += cost
+	b.evaluations++
+}
+
+// The number of evaluations so far
+func (b *Budget) Evaluations() int {
+	if b == nil {
+		return 0
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.evaluations
+}
+
+// The observation phase of a worker that has spent the given evaluation time: a fraction of the share of the worker
+// of the cost budget (or, without one, of the wall-clock time, as measured by clock since the start)
+// Returns nil if the budget has no limits
+func (b *Budget) observation(fraction float64, spent *time.Duration, clock func() time.Duration) observation {
+	switch {
+	case b == nil:
+		return nil
+	case b.cost > 0:
+		share := time.Duration(fraction * float64(b.cost) / float64(b.workers))
+		return func(int) bool {
+			return *spent < share
+		}
+	case b.duration > 0:
+		end := time.Duration(fraction * float64(b.duration))
+		return func(int) bool {
+			return clock() < end
+		}
+	}
+	return nil
+}
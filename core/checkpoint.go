@@ -0,0 +1,314 @@
+package core
+
+import (
+	"encoding/gob"
+	"fmt"
+	"github.com/acflorea/goptim/functions"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// How often the trials of the experiment in progress are saved
+var DefaultCheckpointInterval = time.Minute
+
+// The state of a run saved to vargs["checkpoint"]
+// The generators, the stopping rules and the pruners are not saved: a resumed experiment replays the recorded trials
+// of each worker instead, so they take the same steps without evaluating the function again
+type checkpoint struct {
+	// The seed and the number of workers of the run
+	Seed    int64
+	Workers int
+	// The results of the finished experiments
+	Results []OptimizationOutput
+	// The trials of each worker in the experiment in progress and its wall-clock time
+	Trials  [][]checkpointTrial
+	Elapsed time.Duration
+
+	file     string
+	interval time.Duration
+	// The start of the experiment in progress and the last save
+	start time.Time
+	saved time.Time
+	mutex *sync.Mutex
+}
+
+// A trial as recorded in a checkpoint
+type checkpointTrial struct {
+	// The point (printed) and the budget of the evaluation
+	Point  string
+	Budget float64
+	// The values of the function (all the objectives of a multi-objective function)
+	Values []float64
+	// The intermediate values reported to the pruner and whether the trial was pruned
+	Reports []checkpointReport
+	Pruned  bool
+	// The evaluation time and the wall-clock time of the experiment at the end of the trial
+	Cost time.Duration
+	At   time.Duration
+}
+
+type checkpointReport struct {
+	Step  int
+	Value float64
+}
+
+// The checkpoint of the run, nil if vargs["checkpoint"] names no file
+// If vargs["resume"] is true and the file exists the run continues from it
+func newCheckpoint(vargs map[string]interface{}, seed int64, W int) *checkpoint {
+
+	file := stringArg(vargs, "checkpoint", "")
+	if file == "" {
+		return nil
+	}
+
+	c := &checkpoint{Seed: seed, Workers: W, file: file, mutex: &sync.Mutex{}}
+	c.interval, _ = vargs["checkpointInterval"].(time.Duration)
+	if c.interval <= 0 {
+		c.interval = DefaultCheckpointInterval
+	}
+
+	if !boolArg(vargs, "resume", false) {
+		return c
+	}
+
+	in, err := os.Open(file)
+	if os.IsNotExist(err) {
+		log.Println("No checkpoint in ", file, ", starting a new run")
+		return c
+	}
+	if err != nil {
+		panic(err)
+	}
+	defer in.Close()
+
+	if err := gob.NewDecoder(in).Decode(c); err != nil {
+		panic(fmt.Errorf("invalid checkpoint %s: %v", file, err))
+	}
+	if c.Workers != W {
+		panic(fmt.Errorf("the checkpoint %s has %d workers, not %d", file, c.Workers, W))
+	}
+	return c
+}
+
+// The number of experiments already finished
+func (c *checkpoint) finished() int {
+	if c == nil {
+		return 0
+	}
+	return len(c.Results)
+}
+
+// Starts the next experiment, returns the journal of each of the W workers (nil without a checkpoint)
+// The journals replay the trials recorded for the experiment, if any
+func (c *checkpoint) experiment(W int) []*journal {
+
+	journals := make([]*journal, W)
+	if c == nil {
+		return journals
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for w := range journals {
+		journals[w] = &journal{checkpoint: c, w: w}
+		if w < len(c.Trials) {
+			journals[w].replay = c.Trials[w]
+		}
+	}
+	c.Trials = make([][]checkpointTrial, c.Workers)
+	c.start = time.Now().Add(-c.Elapsed)
+	c.saved = time.Now()
+	return journals
+}
+
+// Records the result of the experiment in progress and saves the checkpoint
+func (c *checkpoint) finish(output OptimizationOutput) {
+	if c == nil {
+		return
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.Results = append(c.Results, output)
+	c.Trials = nil
+	c.Elapsed = 0
+	c.save()
+}
+
+// The wall-clock time of the experiment in progress
+func (c *checkpoint) elapsed() time.Duration {
+	return time.Since(c.start)
+}
+
+// Writes the checkpoint, the previous one is replaced only once the new one is complete
+// The run goes on if the checkpoint cannot be written
+func (c *checkpoint) save() {
+
+	if c.Trials != nil {
+		c.Elapsed = c.elapsed()
+	}
+	c.saved = time.Now()
+
+	out, err := os.Create(c.file + ".tmp")
+	if err != nil {
+		log.Println("Problem writing the checkpoint ", err)
+		return
+	}
+	err = gob.NewEncoder(out).Encode(c)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(c.file+".tmp", c.file)
+	}
+	if err != nil {
+		log.Println("Problem writing the checkpoint ", err)
+	}
+}
+
+// The trials of a worker in the experiment in progress
+type journal struct {
+	checkpoint *checkpoint
+	w          int
+	// The recorded trials not replayed yet
+	replay []checkpointTrial
+	// The last trial and whether it was replayed
+	last     checkpointTrial
+	replayed bool
+}
+
+// The journal in vargs["journal"], nil if there is none
+func journalOf(vargs map[string]interface{}) *journal {
+	j, _ := vargs["journal"].(*journal)
+	return j
+}
+
+// Evaluates a point (with a budget, 1 for the functions without one)
+// The next recorded trial of the worker is replayed instead if it has the same point and budget, otherwise the
+// run took another path and the remaining recorded trials are evaluated again
+func (j *journal) evaluate(point functions.MultidimensionalPoint, budget float64, vargs map[string]interface{},
+	f func(vargs map[string]interface{}) ([]float64, error)) ([]float64, error) {
+
+	key := point.PrettyPrint()
+
+	if len(j.replay) > 0 {
+		next := j.replay[0]
+		j.replay = j.replay[1:]
+		if next.Point == key && next.Budget == budget {
+			for _, report := range next.Reports {
+				functions.Report(vargs, report.Step, report.Value)
+			}
+			j.record(next, true)
+			if next.Pruned {
+				return next.Values, functions.ErrPruned
+			}
+			return next.Values, nil
+		}
+		j.replay = nil
+	}
+
+	trial := checkpointTrial{Point: key, Budget: budget}
+	if reporter, ok := vargs["reporter"].(functions.Reporter); ok {
+		vargs = functions.CopyArgs(vargs)
+		vargs["reporter"] = recordingReporter{reporter: reporter, trial: &trial}
+	}
+
+	start := time.Now()
+	values, err := f(vargs)
+	trial.Cost = time.Since(start)
+	trial.Values = values
+	trial.Pruned = err == functions.ErrPruned
+	trial.At = j.checkpoint.elapsed()
+	j.record(trial, false)
+
+	return values, err
+}
+
+func (j *journal) record(trial checkpointTrial, replayed bool) {
+
+	j.last, j.replayed = trial, replayed
+
+	c := j.checkpoint
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.Trials[j.w] = append(c.Trials[j.w], trial)
+	if !replayed && time.Since(c.saved) >= c.interval {
+		c.save()
+	}
+}
+
+// Records the intermediate values of a trial
+type recordingReporter struct {
+	reporter functions.Reporter
+	trial    *checkpointTrial
+}
+
+func (r recordingReporter) Report(step int, value float64) bool {
+	r.trial.Reports = append(r.trial.Reports, checkpointReport{Step: step, Value: value})
+	return r.reporter.Report(step, value)
+}
+
+// Records the trials of a function
+func (j *journal) numerical(f functions.NumericalFunction) functions.NumericalFunction {
+	if j == nil {
+		return f
+	}
+	return func(point functions.MultidimensionalPoint, vargs map[string]interface{}) (float64, error) {
+		values, err := j.evaluate(point, 1, vargs, func(vargs map[string]interface{}) ([]float64, error) {
+			value, err := f(point, vargs)
+			return []float64{value}, err
+		})
+		return values[0], err
+	}
+}
+
+// Records the trials of a function with a budget
+func (j *journal) budgeted(f functions.BudgetedFunction) functions.BudgetedFunction {
+	if j == nil {
+		return f
+	}
+	return func(point functions.MultidimensionalPoint, budget float64, vargs map[string]interface{}) (float64, error) {
+		values, err := j.evaluate(point, budget, vargs, func(vargs map[string]interface{}) ([]float64, error) {
+			value, err := f(point, budget, vargs)
+			return []float64{value}, err
+		})
+		return values[0], err
+	}
+}
+
+// Records the trials of a multi-objective function
+func (j *journal) multi(f functions.MultiObjectiveFunction) functions.MultiObjectiveFunction {
+	if j == nil {
+		return f
+	}
+	return func(point functions.MultidimensionalPoint, vargs map[string]interface{}) ([]float64, error) {
+		return j.evaluate(point, 1, vargs, func(vargs map[string]interface{}) ([]float64, error) {
+			return f(point, vargs)
+		})
+	}
+}
+
+// The evaluation time of the last trial of the worker, the recorded one if the trial was replayed
+func evaluationTime(vargs map[string]interface{}, start time.Time) time.Duration {
+	if j := journalOf(vargs); j != nil {
+		return j.last.Cost
+	}
+	return time.Since(start)
+}
+
+// The wall-clock time of the experiment at the end of the last trial of the worker
+// (the recorded one if the trial was replayed, so the observation phase ends at the same trial)
+func trialClock(vargs map[string]interface{}, budget *Budget) func() time.Duration {
+	j := journalOf(vargs)
+	return func() time.Duration {
+		if j != nil && j.replayed {
+			return j.last.At
+		}
+		return time.Since(budget.start)
+	}
+}
@@ -16,11 +16,54 @@ type OptimizationOutput struct {
 	Optim  float64
 	GOptim float64
 	X      functions.MultidimensionalPoint
+	// The evaluations of each worker up to its stop, or all of them if it did not stop (the refinement included)
 	Trials int
+	// The phase that found the optimum
+	Phase Phase
+	// The number of trials stopped by the pruner
+	Pruned int
+	// The time budget ran out before the experiment ended
+	Exhausted bool
+	// The non-dominated points of a multi-objective search
+	Front []functions.ParetoPoint
 }
 
+// The phase of Minimize that found a point
+type Phase int
+
+const (
+	// The points of the generator
+	RandomPhase Phase = iota
+	// The local search around the optimum of the random phase
+	RefinementPhase
+)
+
+var phases = [...]string{"random", "refinement"}
+
+func (phase Phase) String() string {
+	return phases[phase]
+}
+
+// Runs noOfExperiments optimization experiments, each with W workers
+// Given the same seed, number of workers and algorithm the experiments generate the same points
+// and take the same stopping decisions (the ManagerWorker algorithm shares a generator between
+// the workers, so it is reproducible only with a single worker)
+// If vargs["budgetedFunction"] is a functions.BudgetedFunction the workers maximize it with Hyperband instead
+// (vargs["minBudget"] and vargs["eta"] configure it)
+// If vargs["multiObjectiveFunction"] is a functions.MultiObjectiveFunction the workers maximize all its objectives,
+// the result of an experiment is the non-dominated set of its points (the optimum reported is the best first
+// objective) and results["front"] is the non-dominated set of all the experiments
+// vargs["timeBudget"] and vargs["costBudget"] (time.Duration) limit the wall-clock time and the total evaluation
+// time of each experiment, the workers stop when either is exhausted and the experiment reports its best point so far
+// vargs["pruner"] names the pruner of the functions that report intermediate values, each worker has its own
+// If vargs["checkpoint"] names a file the results of the experiments and the trials of the experiment in progress
+// are saved to it (every vargs["checkpointInterval"]), with vargs["resume"] the run continues from the file: the
+// finished experiments are kept and the recorded trials are replayed instead of evaluated
+// If no point satisfies the constraints the run stops before the workers start, results["error"] wraps
+// generators.ErrInfeasible
 func Optimize(noOfExperiments int,
 	restrictions []generators.GenerationStrategy,
+	constraints []generators.Constraint,
 	probabilityToChange []float64,
 	adjustSingleValue bool,
 	optimalSlicePercent float64,
@@ -28,6 +71,8 @@ func Optimize(noOfExperiments int,
 	targetstop int,
 	W int,
 	algorithm generators.Algorithm,
+	sampler generators.Sampler,
+	seed int64,
 	targetFunction functions.NumericalFunction,
 	silent bool,
 	vargs map[string]interface{}) map[string]interface{} {
@@ -36,80 +81,212 @@ func Optimize(noOfExperiments int,
 
 	match := 0
 	early := 0
+	refined := 0
+	exhausted := 0
 	var globalTries = 0
+	var globalPruned = 0
+
+	// an empty feasible region is reported here, the generators of the workers could only panic
+	if err := generators.CheckFeasible(restrictions, constraints, seed); err != nil {
+		log.Println("Problem with the search space ", err)
+		return map[string]interface{}{"error": err}
+	}
 
 	OptResults := make([]OptimizationOutput, noOfExperiments)
 
+	// a resumed run keeps its seed
+	checkpoint := newCheckpoint(vargs, seed, W)
+	if checkpoint != nil {
+		seed = checkpoint.Seed
+	}
+	if checkpoint.finished() > noOfExperiments {
+		panic(fmt.Errorf("the checkpoint has %d experiments, more than %d", checkpoint.finished(), noOfExperiments))
+	}
+
+	// Counts the early stops and prints the result of an experiment
+	tally := func(expIndex int) {
+		output := OptResults[expIndex]
+		if output.Phase == RefinementPhase {
+			refined++
+		}
+		if output.Exhausted {
+			exhausted++
+		}
+
+		globalTries += output.Trials
+		globalPruned += output.Pruned
+
+		if output.Trials < maxAttempts && !output.Exhausted {
+			early++
+			if output.Optim == output.GOptim {
+				match++
+			}
+		}
+
+		if !silent {
+			if output.Optim == output.GOptim {
+				fmt.Println("+", expIndex, match, output.Trials, output.X.PrettyPrint(), output.Optim, output.GOptim,
+					output.Phase)
+			} else {
+				fmt.Println("-", expIndex, match, output.Trials, output.X.PrettyPrint(), output.Optim, output.GOptim,
+					output.Phase)
+			}
+		}
+	}
+
+	// the seeds of the experiments are derived from the global one
+	seeds := rand.New(rand.NewSource(seed))
+
 	for expIndex := 0; expIndex < noOfExperiments; expIndex++ {
 
-		tuningTrials := int(math.Max(1, float64(targetstop)/(math.E)))
+		experimentSeed := seeds.Int63()
+
+		// the experiments finished before the run was interrupted
+		if expIndex < checkpoint.finished() {
+			OptResults[expIndex] = checkpoint.Results[expIndex]
+			tally(expIndex)
+			continue
+		}
+		journals := checkpoint.experiment(W)
+		// each worker takes its stopping decisions based on its own seed
+		workerSeeds := rand.New(rand.NewSource(experimentSeed))
+
+		tuningTrials := int(math.Max(1, float64(targetstop)*floatArg(vargs, "observation", 1/math.E)))
 		//tuningTrials := maxAttempts
-		generator :=
-			generators.NewRandom(restrictions, probabilityToChange, adjustSingleValue, optimalSlicePercent, maxAttempts, tuningTrials, W, algorithm)
+		generator := newGenerator(sampler, restrictions, constraints, probabilityToChange, adjustSingleValue,
+			optimalSlicePercent, maxAttempts, tuningTrials, W, algorithm, experimentSeed, vargs)
 
 		// channel used by workers to communicate their results
 		resultsChans := make(chan functions.Sample, W)
 
+		var budget *Budget
+		timeBudget, _ := vargs["timeBudget"].(time.Duration)
+		costBudget, _ := vargs["costBudget"].(time.Duration)
+		if timeBudget > 0 || costBudget > 0 {
+			budget = NewBudget(timeBudget, costBudget, W)
+			if checkpoint != nil {
+				// the resumed experiment continues its clock
+				budget.start = checkpoint.start
+			}
+		}
+
 		for w := 0; w < W; w++ {
 
 			localvargs := map[string]interface{}{}
 			for k, v := range vargs {
 				localvargs[k] = v
 			}
+			if budget != nil {
+				localvargs["budget"] = budget
+			}
+			if journals[w] != nil {
+				localvargs["journal"] = journals[w]
+			}
 
-			go func(w int, ch chan functions.Sample) {
+			go func(w int, workerSeed int64, ch chan functions.Sample) {
 
 				// Add the worker id to the args map
 				localvargs["workerId"] = w
 
-				i, p, v, gv, o := DMaximize(targetFunction, localvargs, generator, targetstop/W, maxAttempts/W, w, true)
+				// The pruner learns from the trials of the worker
+				pruner := newPruner(localvargs)
+				if pruner != nil {
+					localvargs["pruner"] = pruner
+				}
+
+				// the tries of the worker: its evaluations up to the stop, or all of them if it did not stop
+				// (the evaluations of the refinement included)
+				evaluations := 0
+
+				if multi, ok := localvargs["multiObjectiveFunction"].(functions.MultiObjectiveFunction); ok {
+					multi = journals[w].multi(multi)
+					counted := func(x functions.MultidimensionalPoint, vargs map[string]interface{}) ([]float64, error) {
+						evaluations++
+						return multi(x, vargs)
+					}
+					front := MaximizeMulti(counted, localvargs, generator, maxAttempts/W, w)
+					p, v := bestOfFront(front)
+					if !silent {
+						fmt.Println("Worker ", w, " FRONT --> ", len(front), p, v)
+					}
+
+					ch <- functions.Sample{Index: evaluations, Point: p, Value: v, GValue: v, FullSearch: true,
+						Front: front}
+					return
+				}
+
+				if budgeted, ok := localvargs["budgetedFunction"].(functions.BudgetedFunction); ok {
+					// Hyperband runs until the attempts are used, there is no stopping decision
+					e, p, v, cost := Hyperband(functions.NegateBudgeted(journals[w].budgeted(budgeted)), localvargs, generator, maxAttempts/W,
+						floatArg(localvargs, "minBudget", DefaultMinBudget), intArg(localvargs, "eta", DefaultEta), w)
+					if !silent {
+						fmt.Println("Worker ", w, " MAX --> ", e, p, -v, cost)
+					}
+
+					// the tries are counted in full evaluations
+					tries := maxAttempts / W
+					if budget.Exhausted() {
+						tries = int(math.Round(cost))
+					}
+					ch <- functions.Sample{Index: tries, Point: p, Value: -v, GValue: -v, FullSearch: true}
+					return
+				}
+
+				f := journals[w].numerical(targetFunction)
+				counted := func(x functions.MultidimensionalPoint, vargs map[string]interface{}) (float64, error) {
+					evaluations++
+					return f(x, vargs)
+				}
+				i, p, v, gv, o, phase := DMaximize(counted, localvargs, generator, targetstop/W, maxAttempts/W, w, workerSeed, true)
 				if !silent {
-					fmt.Println("Worker ", w, " MAX --> ", i, p, v, gv, o)
+					fmt.Println("Worker ", w, " MAX --> ", i, p, v, gv, o, phase)
+				}
+
+				pruned := 0
+				if pruner != nil {
+					pruned = pruner.Pruned()
 				}
 
-				ch <- functions.Sample{i, p, v, gv, o == 0}
-			}(w, resultsChans)
+				// i is the attempt of the stop
+				tries := i + 1
+				if o == 0 {
+					tries = evaluations
+				}
+				ch <- functions.Sample{Index: tries, Point: p, Value: v, GValue: gv, FullSearch: o == 0,
+					Refined: phase == RefinementPhase, Pruned: pruned}
+			}(w, workerSeeds.Int63(), resultsChans)
 		}
 
 		// Collect results
 		results := make([]functions.Sample, W)
 		totalTries := 0
+		pruned := 0
+		var front []functions.ParetoPoint
 		optim, goptim := -math.MaxFloat64, -math.MaxFloat64
 		var point functions.MultidimensionalPoint
+		phase := RandomPhase
 		for i := 0; i < W; i++ {
 			results[i] = <-resultsChans
-			if results[i].FullSearch {
-				totalTries += maxAttempts / W
-			} else {
-				totalTries += results[i].Index
-			}
+			pruned += results[i].Pruned
+			front = append(front, results[i].Front...)
+			totalTries += results[i].Index
 			if optim < results[i].Value {
 				optim = results[i].Value
 				point = results[i].Point
+				phase = RandomPhase
+				if results[i].Refined {
+					phase = RefinementPhase
+				}
 			}
 			if goptim < results[i].GValue {
 				goptim = results[i].GValue
 			}
 		}
 
-		OptResults[expIndex] = OptimizationOutput{optim, goptim, point, totalTries}
-
-		globalTries += totalTries
-
-		if totalTries < maxAttempts {
-			early++
-			if optim == goptim {
-				match++
-			}
-		}
-
-		if !silent {
-			if optim == goptim {
-				fmt.Println("+", expIndex, match, totalTries, point.PrettyPrint(), optim, goptim)
-			} else {
-				fmt.Println("-", expIndex, match, totalTries, point.PrettyPrint(), optim, goptim)
-			}
-		}
+		OptResults[expIndex] = OptimizationOutput{Optim: optim, GOptim: goptim, X: point, Trials: totalTries, Phase: phase,
+			Pruned: pruned, Front: maximal(front), Exhausted: budget.Exhausted()}
+		checkpoint.finish(OptResults[expIndex])
+		tally(expIndex)
 	}
 
 	fmt.Println()
@@ -149,6 +326,15 @@ func Optimize(noOfExperiments int,
 	fmt.Println(fmt.Sprintf("Average number of attempts %f (%f percent faster) ", avgTrials,
 		(float64(maxAttempts)-avgTrials)/float64(maxAttempts)*100))
 
+	refinedPercent := float64(refined) / float64(noOfExperiments)
+	fmt.Println(fmt.Sprintf("The refinement phase found the optimum in %d (%f) cases", refined, refinedPercent))
+
+	prunedPercent := float64(globalPruned) / float64(globalTries)
+	fmt.Println(fmt.Sprintf("%d (%f) of the trials were pruned", globalPruned, prunedPercent))
+
+	exhaustedPercent := float64(exhausted) / float64(noOfExperiments)
+	fmt.Println(fmt.Sprintf("The time budget ran out in %d (%f) cases", exhausted, exhaustedPercent))
+
 	fmt.Println(fmt.Sprintf("Optimisation best and global best results are %f, %f", best, gbest))
 
 	fmt.Println(fmt.Sprintf("(ES) Optimisation average result and standard deviation are %f, %f", avg, std))
@@ -164,6 +350,22 @@ func Optimize(noOfExperiments int,
 	results["avg"] = avg
 	results["std"] = std
 	results["optimalSlicePercent"] = optimalSlicePercent
+	results["refinedPercent"] = refinedPercent
+	results["prunedPercent"] = prunedPercent
+	results["exhaustedPercent"] = exhaustedPercent
+
+	if _, ok := vargs["multiObjectiveFunction"].(functions.MultiObjectiveFunction); ok {
+		front := []functions.ParetoPoint{}
+		for expIndex := 0; expIndex < noOfExperiments; expIndex++ {
+			front = append(front, OptResults[expIndex].Front...)
+		}
+		front = maximal(front)
+		fmt.Println(fmt.Sprintf("The Pareto front has %d points", len(front)))
+		for _, point := range front {
+			fmt.Println(point.Point.PrettyPrint(), point.Values)
+		}
+		results["front"] = front
+	}
 
 	fmt.Println("[optimalSlicePercent, earlyStopPercent, matchStopPercent, matchPercent, avg, std]")
 	fmt.Println(fmt.Sprintf("[%f, %f, %f, %f, %f, %f]",
@@ -172,39 +374,148 @@ func Optimize(noOfExperiments int,
 	return results
 }
 
+// Creates the generator of an experiment
+// Options that are specific to a sampler are read from vargs
+func newGenerator(sampler generators.Sampler,
+	restrictions []generators.GenerationStrategy,
+	constraints []generators.Constraint,
+	probabilityToChange []float64,
+	adjustSingleValue bool,
+	optimalSlicePercent float64,
+	pointsNo int,
+	minPointsNo int,
+	W int,
+	algorithm generators.Algorithm,
+	seed int64,
+	vargs map[string]interface{}) generators.Generator {
+
+	switch sampler {
+	case generators.Sobol:
+		return generators.NewSobol(restrictions, constraints, pointsNo, W, algorithm, seed)
+	case generators.Halton:
+		return generators.NewHalton(restrictions, constraints, pointsNo, W, algorithm, seed)
+	case generators.LatinHypercube:
+		return generators.NewLatinHypercube(restrictions, constraints, pointsNo, W, algorithm, seed)
+	case generators.Grid:
+		resolution := intArg(vargs, "gridResolution", generators.DefaultGridResolution)
+		return generators.NewGrid(restrictions, constraints, resolution, W, algorithm)
+	case generators.GaussianProcess:
+		acquisition, ok := generators.Acquisitions[stringArg(vargs, "acquisition", "EI")]
+		if !ok {
+			panic(fmt.Errorf("unknown acquisition function %v", vargs["acquisition"]))
+		}
+		return generators.NewGaussianProcess(restrictions, constraints, acquisition,
+			intArg(vargs, "initialPoints", generators.DefaultInitialPoints), pointsNo, W, algorithm, seed)
+	case generators.TPE:
+		return generators.NewTPE(restrictions, constraints,
+			intArg(vargs, "initialPoints", generators.DefaultInitialPoints), pointsNo, W, algorithm, seed)
+	case generators.CMAES:
+		return generators.NewCMAES(restrictions, constraints, boolArg(vargs, "ipop", true), pointsNo, W, seed)
+	case generators.DifferentialEvolution:
+		strategy, ok := generators.DEStrategies[stringArg(vargs, "deStrategy", "rand/1/bin")]
+		if !ok {
+			panic(fmt.Errorf("unknown differential evolution strategy %v", vargs["deStrategy"]))
+		}
+		return generators.NewDifferentialEvolution(restrictions, constraints, strategy, pointsNo, W, seed)
+	case generators.GeneticAlgorithm:
+		return generators.NewGeneticAlgorithm(restrictions, constraints, pointsNo, W, seed)
+	case generators.PSO:
+		return generators.NewPSO(restrictions, constraints, pointsNo, W, seed)
+	case generators.NSGA2:
+		return generators.NewNSGA2(restrictions, constraints, pointsNo, W, seed)
+	case generators.Annealing:
+		schedule, ok := generators.CoolingSchedules[stringArg(vargs, "cooling", "Exponential")]
+		if !ok {
+			panic(fmt.Errorf("unknown cooling schedule %v", vargs["cooling"]))
+		}
+		return generators.NewAnnealing(restrictions, constraints, schedule,
+			floatArg(vargs, "temperature", generators.DefaultTemperature), pointsNo, W, seed)
+	}
+
+	adaptation, ok := generators.Adaptations[stringArg(vargs, "adaptation", "Fixed")]
+	if !ok {
+		panic(fmt.Errorf("unknown adaptation %v", vargs["adaptation"]))
+	}
+	return generators.NewRandom(restrictions, constraints, probabilityToChange, adaptation, adjustSingleValue,
+		optimalSlicePercent, pointsNo, minPointsNo, W, algorithm, seed)
+}
+
+// An optional int argument
+func intArg(vargs map[string]interface{}, name string, defaultValue int) int {
+	if value, ok := vargs[name].(int); ok {
+		return value
+	}
+	return defaultValue
+}
+
+// An optional float64 argument
+func floatArg(vargs map[string]interface{}, name string, defaultValue float64) float64 {
+	if value, ok := vargs[name].(float64); ok {
+		return value
+	}
+	return defaultValue
+}
+
+// An optional bool argument
+func boolArg(vargs map[string]interface{}, name string, defaultValue bool) bool {
+	if value, ok := vargs[name].(bool); ok {
+		return value
+	}
+	return defaultValue
+}
+
+// An optional string argument
+func stringArg(vargs map[string]interface{}, name string, defaultValue string) string {
+	if value, ok := vargs[name].(string); ok && value != "" {
+		return value
+	}
+	return defaultValue
+}
+
 // Attempts to dynamically minimize the function f
-// k := n / (2 * math.E)
+// k := n / e (or the fraction vargs["observation"] of n)
 // 1st it evaluated the function in k random points and computes the minimum
 // it then continues to evaluate the function (up to a total maximum of n attempts)
 // The algorithm stops either if a value found at the second step is lower than the minimum
 // of if n attempts have been made (in which case the 1st step minimum is reported)
 // w is thw worker index
-func DMinimize(f functions.NumericalFunction, vargs map[string]interface{}, generator generators.Generator, n, N, w int, goAllTheWay bool) (
+// seed initializes the random generator behind the stopping decisions
+func DMinimize(f functions.NumericalFunction, vargs map[string]interface{}, generator generators.Generator, n, N, w int, seed int64, goAllTheWay bool) (
 	index int,
 	p functions.MultidimensionalPoint,
 	min float64,
 	gmin float64,
-	optimNo int) {
+	optimNo int,
+	phase Phase) {
 
-	k := int(math.Max(1, float64(n)/math.E))
-	return Minimize(f, vargs, generator, k, N, w, goAllTheWay)
+	k := int(math.Max(1, float64(n)*floatArg(vargs, "observation", 1/math.E)))
+	return Minimize(f, vargs, generator, k, N, w, seed, goAllTheWay)
 }
 
 // Attempts to minimize the function f
 // vargs are passed to the function
 // 1st it evaluated the function in k random points and computes the minimum
 // it then continues to evaluate the function (up to a total maximum of n attempts)
-// The algorithm stops either if a value found at the second step is lower than the minimum
+// The algorithm stops either if the stopping rule accepts a point (by default a value found at the second step
+// that is lower than the minimum, with a probability that grows with each such value, see newStoppingRule)
 // of if n attempts have been made (in which case the 1st step minimum is reported)
+// optimNo is the number of minimums found at the second step (at least 1 if the rule stopped the search, 0 otherwise)
 // gmin is the global minimum (if goAllTheWay then the algorithm continues and computes it
 // for comparison purposes)
 // w is the worker index
-func Minimize(f functions.NumericalFunction, vargs map[string]interface{}, generator generators.Generator, k, N, w int, goAllTheWay bool) (
+// seed initializes the random generator behind the stopping decisions
+// If vargs["refinement"] is a positive fraction and the generator is Refinable, the point where the algorithm
+// stops is refined with a local search that uses that fraction of the remaining attempts
+// (index is then the last attempt of the refinement and phase tells which of the two found min)
+// If vargs["budget"] is a *Budget the search also stops when it is exhausted, the observation phase is then
+// the fraction vargs["observation"] (1/e by default) of the share of the worker of the budget
+func Minimize(f functions.NumericalFunction, vargs map[string]interface{}, generator generators.Generator, k, N, w int, seed int64, goAllTheWay bool) (
 	index int,
 	p functions.MultidimensionalPoint,
 	min float64,
 	gmin float64,
-	optimNo int) {
+	optimNo int,
+	phase Phase) {
 
 	index = -1
 	min = math.MaxFloat64
@@ -213,6 +524,17 @@ func Minimize(f functions.NumericalFunction, vargs map[string]interface{}, gener
 
 	minReached := false
 
+	// the evaluation time of the worker
+	var spent time.Duration
+	budget := budgetOf(vargs)
+	observing := budget.observation(floatArg(vargs, "observation", 1/math.E), &spent, trialClock(vargs, budget))
+	if observing == nil {
+		observing = firstPoints(k)
+	}
+	rule := newStoppingRule(vargs, observing, rand.New(rand.NewSource(seed)))
+
+	refinement := floatArg(vargs, "refinement", 0)
+
 	api, slackEnabled := vargs["slackAPI"].(*slack.Slack)
 	slackChannel, ok := vargs["slackChannel"].(string)
 	if !ok {
@@ -233,34 +555,51 @@ func Minimize(f functions.NumericalFunction, vargs map[string]interface{}, gener
 	}
 
 	state := generators.GeneratorState{
-		[]functions.MultidimensionalPoint{},
-		[]functions.TwoDPointVector{},
-		[]float64{},
-		functions.MultidimensionalPoint{}}
-
-	for i := 0; i < N; i++ {
-
-		rndPoint, newState := generator.Next(w, state)
-		f_rnd, _ := f(rndPoint, vargs)
-		centroid := newState.Centroid
-
+		GeneratedPoints: []functions.MultidimensionalPoint{},
+		Statistics:      []functions.TwoDPointVector{},
+		Output:          []float64{},
+		Centroid:        functions.MultidimensionalPoint{}}
+
+	pruner := newPruner(vargs)
+
+	evaluate := func(point functions.MultidimensionalPoint) (float64, bool) {
+		start := time.Now()
+		value, pruned := evaluateTrial(f, point, vargs, pruner)
+		cost := evaluationTime(vargs, start)
+		spent += cost
+		budget.Spend(cost)
 		if slackEnabled {
-			err := api.ChatPostMessage(slackChannel, fmt.Sprintf("[w=%d] %s", w, functions.FloatToString(f_rnd)+" :: "+rndPoint.PrettyPrint()), nil)
+			err := api.ChatPostMessage(slackChannel, fmt.Sprintf("[w=%d] %s", w, functions.FloatToString(value)+" :: "+point.PrettyPrint()), nil)
 			if err != nil {
 				log.Println("Problem connecting to Slack ", err)
 			}
 		}
+		return value, pruned
+	}
+
+	for i := 0; i < N && generator.HasNext(w) && !budget.Exhausted(); i++ {
+
+		rndPoint, newState := generator.Next(w, state)
+		f_rnd, pruned := evaluate(rndPoint)
+		centroid := newState.Centroid
+		prunedPoints := newState.Pruned
+		if pruned {
+			prunedPoints = append(prunedPoints, len(newState.Output))
+		}
 
 		if i == 0 {
 			// in case the centroid was not initialized
 			centroid = rndPoint
 		}
 
-		if minReached {
+		if pruned {
+			// a pruned trial has no final value, it can be neither the minimum nor the stopping point
+		} else if minReached {
 			if f_rnd < gmin {
 				gmin = f_rnd
 			}
 		} else {
+			stop := rule.Stop(i, f_rnd, min)
 			if f_rnd < min {
 				// the centroid is tmpOpt
 				centroid = rndPoint
@@ -270,28 +609,43 @@ func Minimize(f functions.NumericalFunction, vargs map[string]interface{}, gener
 				min = f_rnd
 				gmin = min
 
-				if i > k {
-					if accept(optimNo) {
-						//if acceptAll() {
-						minReached = true
-						// Increase the number of optimum points found
-						optimNo += 1
-						if !goAllTheWay {
-							break
+				if !observing(i) {
+					// Increase the number of optimum points found
+					optimNo += 1
+				}
+			}
+			if stop {
+				minReached = true
+				index = i
+				if optimNo == 0 {
+					// the rule stopped without a new optimum (e.g. out of patience)
+					optimNo = 1
+				}
+				if refinable, ok := generator.(generators.Refinable); ok && refinement > 0 {
+					attempts := int(refinement * float64(N-i-1))
+					if refiner := refinable.Refine(p, min, attempts); refiner != nil {
+						used, rp, rmin := refine(evaluate, refiner, budget, w)
+						i += used
+						index = i
+						if rmin < min {
+							p, min, phase = rp, rmin, RefinementPhase
 						}
-					} else {
-						// Increase the number of optimum points found
-						optimNo += 1
+						gmin = math.Min(gmin, min)
 					}
 				}
+				if !goAllTheWay {
+					break
+				}
 			}
 		}
 
 		state = generators.GeneratorState{
-			newState.GeneratedPoints,
-			newState.Statistics,
-			append(newState.Output, f_rnd),
-			centroid}
+			GeneratedPoints: newState.GeneratedPoints,
+			Statistics:      newState.Statistics,
+			Output:          append(newState.Output, f_rnd),
+			Centroid:        centroid,
+			Pruned:          prunedPoints,
+			Probabilities:   newState.Probabilities}
 	}
 
 	if !minReached {
@@ -302,35 +656,57 @@ func Minimize(f functions.NumericalFunction, vargs map[string]interface{}, gener
 	return
 }
 
-func acceptAll() bool {
-	return true
-}
+// Evaluates the points of the refinement generator (while the budget lasts)
+// Returns the number of points and the best of them
+func refine(evaluate func(functions.MultidimensionalPoint) (float64, bool), refiner generators.Generator,
+	budget *Budget, w int) (
+	used int,
+	p functions.MultidimensionalPoint,
+	min float64) {
 
-func accept(optimNo int) bool {
-	s := rand.NewSource(time.Now().UnixNano())
-	return rand.New(s).Float64() < 0.1+(0.1*float64(optimNo))
+	min = math.MaxFloat64
+	state := generators.GeneratorState{}
+	for ; refiner.HasNext(w) && !budget.Exhausted(); used++ {
+		var point functions.MultidimensionalPoint
+		point, state = refiner.Next(w, state)
+		if len(point.Values) == 0 {
+			// the search converged
+			break
+		}
+		value, pruned := evaluate(point)
+		if pruned {
+			state.Pruned = append(state.Pruned, len(state.Output))
+		}
+		state.Output = append(state.Output, value)
+		if !pruned && value < min {
+			p, min = point, value
+		}
+	}
+	return
 }
 
 // Dynamically Minimizes the negation of the target function
-func DMaximize(f functions.NumericalFunction, vargs map[string]interface{}, generator generators.Generator, n, N, w int, goAllTheWay bool) (
+func DMaximize(f functions.NumericalFunction, vargs map[string]interface{}, generator generators.Generator, n, N, w int, seed int64, goAllTheWay bool) (
 	index int,
 	p functions.MultidimensionalPoint,
 	max float64,
 	gmax float64,
-	optimNo int) {
+	optimNo int,
+	phase Phase) {
 
-	index, p, max, gmax, optimNo = DMinimize(functions.Negate(f), vargs, generator, n, N, w, goAllTheWay)
-	return index, p, -max, -gmax, optimNo
+	index, p, max, gmax, optimNo, phase = DMinimize(functions.Negate(f), negatedTarget(vargs), generator, n, N, w, seed, goAllTheWay)
+	return index, p, -max, -gmax, optimNo, phase
 }
 
 // Minimizes the negation of the target function
-func Maximize(f functions.NumericalFunction, vargs map[string]interface{}, generator generators.Generator, k, n, N, w int, goAllTheWay bool) (
+func Maximize(f functions.NumericalFunction, vargs map[string]interface{}, generator generators.Generator, k, n, N, w int, seed int64, goAllTheWay bool) (
 	index int,
 	p functions.MultidimensionalPoint,
 	max float64,
 	gmax float64,
-	optimNo int) {
+	optimNo int,
+	phase Phase) {
 
-	index, p, max, gmax, optimNo = Minimize(functions.Negate(f), vargs, generator, k, N, w, goAllTheWay)
-	return index, p, -max, -gmax, optimNo
+	index, p, max, gmax, optimNo, phase = Minimize(functions.Negate(f), negatedTarget(vargs), generator, k, N, w, seed, goAllTheWay)
+	return index, p, -max, -gmax, optimNo, phase
 }
@@ -0,0 +1,184 @@
+package core
+
+import (
+	"fmt"
+	"github.com/acflorea/goptim/functions"
+	"github.com/acflorea/goptim/generators"
+	"math"
+	"sort"
+	"time"
+)
+
+// The smallest fraction of the full budget Hyperband evaluates points with
+var DefaultMinBudget = 1.0 / 27
+
+// The reduction factor of successive halving, only the best 1/eta points of a rung are promoted to the next one
+var DefaultEta = 3
+
+// Runs one bracket of successive halving
+// n points of the generator are evaluated with the budget minBudget, then the best 1/eta of them
+// are evaluated again with eta times the budget and so on until the full budget (1) is reached
+// Returns the number of evaluations, the best point at the full budget, its value and the cost of the
+// bracket in full evaluations
+func SuccessiveHalving(f functions.BudgetedFunction, vargs map[string]interface{}, generator generators.Generator,
+	n int, minBudget float64, eta int, w int) (
+	evaluations int,
+	p functions.MultidimensionalPoint,
+	min float64,
+	cost float64) {
+
+	checkHalving(minBudget, eta)
+
+	_, evaluations, p, min, cost = successiveHalving(f, vargs, generator, emptyState(), n, minBudget, eta, w)
+	return
+}
+
+// Runs Hyperband, brackets of successive halving that trade the number of points for their starting budget
+// (from many points with the budget minBudget to few points with the full budget), for as long as the brackets fit
+// in N full evaluations or the generator has points left
+// The generator learns the values of the points at their first (lowest) budget
+// Returns the number of evaluations, the best point at the full budget, its value and the cost of the search
+// in full evaluations
+func Hyperband(f functions.BudgetedFunction, vargs map[string]interface{}, generator generators.Generator,
+	N int, minBudget float64, eta int, w int) (
+	evaluations int,
+	p functions.MultidimensionalPoint,
+	min float64,
+	cost float64) {
+
+	checkHalving(minBudget, eta)
+
+	// the number of rungs of the most exploratory bracket (minus one)
+	sMax := int(math.Floor(math.Log(1/minBudget)/math.Log(float64(eta)) + 1e-9))
+	if float64(N) < bracketCost(sMax, sMax, eta) {
+		panic(fmt.Errorf("%d full evaluations are not enough for a Hyperband bracket", N))
+	}
+
+	min = math.MaxFloat64
+	state := emptyState()
+	budget := budgetOf(vargs)
+
+	for fits := true; fits && generator.HasNext(w) && !budget.Exhausted(); {
+		fits = false
+		for s := sMax; s >= 0 && generator.HasNext(w) && !budget.Exhausted(); s-- {
+			if cost+bracketCost(s, sMax, eta) > float64(N) {
+				continue
+			}
+			fits = true
+
+			var bracketEvaluations int
+			var bp functions.MultidimensionalPoint
+			var bmin, bcost float64
+			state, bracketEvaluations, bp, bmin, bcost = successiveHalving(f, vargs, generator, state,
+				bracketSize(s, sMax, eta), math.Pow(float64(eta), -float64(s)), eta, w)
+
+			evaluations += bracketEvaluations
+			cost += bcost
+			if bmin < min {
+				p, min = bp, bmin
+				state.Centroid = p
+			}
+		}
+	}
+
+	return
+}
+
+// Panics on invalid successive halving settings
+func checkHalving(minBudget float64, eta int) {
+	if minBudget <= 0 || minBudget > 1 {
+		panic(fmt.Errorf("invalid minimum budget %f, it should be in (0, 1]", minBudget))
+	}
+	if eta < 2 {
+		panic(fmt.Errorf("invalid reduction factor %d, it should be at least 2", eta))
+	}
+}
+
+// The number of points of the bracket that starts with the budget eta^-s
+func bracketSize(s, sMax, eta int) int {
+	return int(math.Ceil(float64(sMax+1) / float64(s+1) * math.Pow(float64(eta), float64(s))))
+}
+
+// The cost of the bracket that starts with the budget eta^-s, in full evaluations
+func bracketCost(s, sMax, eta int) (cost float64) {
+	n := bracketSize(s, sMax, eta)
+	for budget := math.Pow(float64(eta), -float64(s)); ; budget = math.Min(1, budget*float64(eta)) {
+		cost += float64(n) * budget
+		if budget >= 1 {
+			return
+		}
+		n = promoted(n, eta)
+	}
+}
+
+// The number of points promoted from a rung of n points
+func promoted(n, eta int) int {
+	return int(math.Max(1, math.Floor(float64(n)/float64(eta))))
+}
+
+func emptyState() generators.GeneratorState {
+	return generators.GeneratorState{
+		GeneratedPoints: []functions.MultidimensionalPoint{},
+		Statistics:      []functions.TwoDPointVector{},
+		Output:          []float64{},
+		Centroid:        functions.MultidimensionalPoint{}}
+}
+
+// A bracket of successive halving that continues the generator state
+func successiveHalving(f functions.BudgetedFunction, vargs map[string]interface{}, generator generators.Generator,
+	initialState generators.GeneratorState, n int, budget float64, eta int, w int) (
+	state generators.GeneratorState,
+	evaluations int,
+	p functions.MultidimensionalPoint,
+	min float64,
+	cost float64) {
+
+	state = initialState
+	min = math.MaxFloat64
+	timeBudget := budgetOf(vargs)
+
+	evaluate := func(point functions.MultidimensionalPoint) float64 {
+		start := time.Now()
+		value, _ := f(point, budget, vargs)
+		timeBudget.Spend(evaluationTime(vargs, start))
+		evaluations++
+		cost += budget
+		if math.IsNaN(value) {
+			return math.Inf(1)
+		}
+		return value
+	}
+
+	// the first rung, new points of the generator
+	rung := []functions.Sample{}
+	for i := 0; i < n && generator.HasNext(w) && !timeBudget.Exhausted(); i++ {
+		var point functions.MultidimensionalPoint
+		point, state = generator.Next(w, state)
+		value := evaluate(point)
+		state.Output = append(state.Output, value)
+		rung = append(rung, functions.Sample{Index: i, Point: point, Value: value})
+	}
+	if len(rung) == 0 {
+		return
+	}
+
+	for budget < 1 && !timeBudget.Exhausted() {
+		// stable, so ties keep the generation order
+		sort.SliceStable(rung, func(i, j int) bool {
+			return rung[i].Value < rung[j].Value
+		})
+		rung = rung[:promoted(len(rung), eta)]
+		budget = math.Min(1, budget*float64(eta))
+		for i := range rung {
+			rung[i].Value = evaluate(rung[i].Point)
+		}
+	}
+
+	for _, sample := range rung {
+		if sample.Value < min {
+			p, min = sample.Point, sample.Value
+		}
+	}
+
+	return
+}
@@ -0,0 +1,111 @@
+package core
+
+import (
+	"github.com/acflorea/goptim/functions"
+	"github.com/acflorea/goptim/generators"
+	"math"
+	"time"
+)
+
+// Minimizes all the objectives of f at once, evaluating N points of the generator
+// The generators see the values of all the objectives in GeneratorState.Objectives and the first one in Output
+// There is no single optimum to stop at, the search goes on until the N points are evaluated
+// Returns the non-dominated points (the Pareto front)
+func MinimizeMulti(f functions.MultiObjectiveFunction, vargs map[string]interface{}, generator generators.Generator,
+	N, w int) (front []functions.ParetoPoint) {
+
+	state := emptyState()
+	state.Objectives = [][]float64{}
+	budget := budgetOf(vargs)
+
+	for i := 0; i < N && generator.HasNext(w) && !budget.Exhausted(); i++ {
+
+		var point functions.MultidimensionalPoint
+		point, state = generator.Next(w, state)
+
+		start := time.Now()
+		values, _ := f(point, vargs)
+		budget.Spend(evaluationTime(vargs, start))
+		for idx, value := range values {
+			// failed evaluations are the worst possible
+			if math.IsNaN(value) {
+				values[idx] = math.Inf(1)
+			}
+		}
+		first := math.Inf(1)
+		if len(values) > 0 {
+			first = values[0]
+		}
+
+		state.Output = append(state.Output, first)
+		state.Objectives = append(state.Objectives, values)
+
+		if onFront(front, values) {
+			// the search moves towards the new non-dominated points
+			state.Centroid = point
+			front = functions.NonDominated(append(front, functions.ParetoPoint{Point: point, Values: values}))
+		}
+	}
+
+	return
+}
+
+// Minimizes the negation of all the objectives of the target function
+func MaximizeMulti(f functions.MultiObjectiveFunction, vargs map[string]interface{}, generator generators.Generator,
+	N, w int) (front []functions.ParetoPoint) {
+
+	front = MinimizeMulti(functions.NegateMulti(f), vargs, generator, N, w)
+	for idx := range front {
+		front[idx].Values = negated(front[idx].Values)
+	}
+	return
+}
+
+// The points that are not dominated by any other when all the objectives are maximized
+func maximal(points []functions.ParetoPoint) []functions.ParetoPoint {
+	negatedPoints := make([]functions.ParetoPoint, len(points))
+	for idx, point := range points {
+		negatedPoints[idx] = functions.ParetoPoint{Point: point.Point, Values: negated(point.Values)}
+	}
+	front := functions.NonDominated(negatedPoints)
+	for idx := range front {
+		front[idx].Values = negated(front[idx].Values)
+	}
+	return front
+}
+
+// Checks if no point of the front is at least as good as the values on every objective
+func onFront(front []functions.ParetoPoint, values []float64) bool {
+	for _, point := range front {
+		covered := true
+		for i := range values {
+			if point.Values[i] > values[i] {
+				covered = false
+				break
+			}
+		}
+		if covered {
+			return false
+		}
+	}
+	return true
+}
+
+func negated(values []float64) []float64 {
+	result := make([]float64, len(values))
+	for i, value := range values {
+		result[i] = -value
+	}
+	return result
+}
+
+// The point of the front with the highest first objective
+func bestOfFront(front []functions.ParetoPoint) (p functions.MultidimensionalPoint, max float64) {
+	max = -math.MaxFloat64
+	for _, point := range front {
+		if len(point.Values) > 0 && point.Values[0] > max {
+			p, max = point.Point, point.Values[0]
+		}
+	}
+	return
+}
@@ -0,0 +1,143 @@
+package core
+
+import (
+	"fmt"
+	"github.com/acflorea/goptim/functions"
+	"math"
+	"sort"
+)
+
+// The number of finished trials a pruner waits for before it prunes
+var DefaultPruningMinTrials = 5
+
+// Decides which trials are stopped early from their intermediate values (lower is better)
+type Pruner interface {
+	// Whether a trial whose best intermediate value up to step is value should be stopped
+	Prune(step int, value float64) bool
+	// Records the best intermediate values (by step) of a finished trial, pruned or not
+	Record(values map[int]float64, pruned bool)
+	// The number of trials pruned so far
+	Pruned() int
+}
+
+// Prunes the trials that are worse than a percentile of the previous trials at the same step
+type percentilePruner struct {
+	// The percentile (between 0 and 100) a trial has to reach to continue
+	percentile float64
+	// The steps before which no trial is pruned
+	warmupSteps int
+	// The number of trials that should reach a step before the trials are pruned at that step
+	minTrials int
+	// The best intermediate values of the finished trials, by step
+	values map[int][]float64
+	// The number of pruned trials
+	pruned int
+}
+
+// Prunes the trials whose best intermediate value is worse than the median of the previous trials at the same step
+func NewMedianPruner(warmupSteps, minTrials int) Pruner {
+	return NewPercentilePruner(50, warmupSteps, minTrials)
+}
+
+// Prunes the trials whose best intermediate value is worse than the percentile of the previous trials
+// at the same step (a lower percentile prunes more)
+func NewPercentilePruner(percentile float64, warmupSteps, minTrials int) Pruner {
+	if percentile < 0 || percentile > 100 {
+		panic(fmt.Errorf("invalid percentile %f, it should be between 0 and 100", percentile))
+	}
+	return &percentilePruner{
+		percentile:  percentile,
+		warmupSteps: warmupSteps,
+		minTrials:   minTrials,
+		values:      map[int][]float64{},
+	}
+}
+
+func (p *percentilePruner) Prune(step int, value float64) bool {
+	values := p.values[step]
+	if step < p.warmupSteps || len(values) == 0 || len(values) < p.minTrials {
+		return false
+	}
+	return value > percentile(values, p.percentile)
+}
+
+func (p *percentilePruner) Record(values map[int]float64, pruned bool) {
+	for step, value := range values {
+		p.values[step] = append(p.values[step], value)
+	}
+	if pruned {
+		p.pruned++
+	}
+}
+
+func (p *percentilePruner) Pruned() int {
+	return p.pruned
+}
+
+// The percentile of the values, interpolated between the closest ranks
+func percentile(values []float64, percentile float64) float64 {
+	sorted := append([]float64{}, values...)
+	sort.Float64s(sorted)
+	rank := percentile / 100 * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	if lower == len(sorted)-1 {
+		return sorted[lower]
+	}
+	return sorted[lower] + (rank-float64(lower))*(sorted[lower+1]-sorted[lower])
+}
+
+// The pruner in vargs["pruner"], either a Pruner or the name of one (Median or Percentile), nil if there is none
+func newPruner(vargs map[string]interface{}) Pruner {
+	if pruner, ok := vargs["pruner"].(Pruner); ok {
+		return pruner
+	}
+	warmup := intArg(vargs, "pruningWarmup", 0)
+	minTrials := intArg(vargs, "pruningMinTrials", DefaultPruningMinTrials)
+	switch stringArg(vargs, "pruner", "") {
+	case "":
+		return nil
+	case "Median":
+		return NewMedianPruner(warmup, minTrials)
+	case "Percentile":
+		return NewPercentilePruner(floatArg(vargs, "pruningPercentile", 25), warmup, minTrials)
+	}
+	panic(fmt.Errorf("unknown pruner %v", vargs["pruner"]))
+}
+
+// Passes the intermediate values of a trial to a pruner
+type trialReporter struct {
+	pruner Pruner
+	// The best value up to each reported step
+	values map[int]float64
+	best   float64
+}
+
+func newTrialReporter(pruner Pruner) *trialReporter {
+	return &trialReporter{pruner: pruner, values: map[int]float64{}, best: math.Inf(1)}
+}
+
+func (t *trialReporter) Report(step int, value float64) bool {
+	t.best = math.Min(t.best, value)
+	t.values[step] = t.best
+	return t.pruner.Prune(step, t.best)
+}
+
+// Evaluates f at the point, letting the pruner stop the evaluation if the function reports intermediate values
+// Returns the value (the last intermediate value if the trial was pruned) and whether the trial was pruned
+func evaluateTrial(f functions.NumericalFunction, point functions.MultidimensionalPoint,
+	vargs map[string]interface{}, pruner Pruner) (value float64, pruned bool) {
+
+	if pruner == nil {
+		value, _ = f(point, vargs)
+		return
+	}
+
+	reporter := newTrialReporter(pruner)
+	trialVargs := functions.CopyArgs(vargs)
+	trialVargs["reporter"] = reporter
+
+	value, err := f(point, trialVargs)
+	pruned = err == functions.ErrPruned
+	pruner.Record(reporter.values, pruned)
+	return
+}
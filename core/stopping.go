@@ -0,0 +1,173 @@
+package core
+
+import (
+	"fmt"
+	"github.com/acflorea/goptim/functions"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// The number of points without improvement after which the Patience rule stops
+var DefaultPatience = 50
+
+// Decides where Minimize stops, it sees the values of the points in the order they are evaluated
+type StoppingRule interface {
+	// Whether the search stops at the i-th point, min is the best value before it
+	Stop(i int, value, min float64) bool
+}
+
+// Creates the stopping rule of a search, each worker and each experiment gets its own
+type StoppingRuleFactory func() StoppingRule
+
+// Checks if the i-th point is part of the observation phase
+type observation func(i int) bool
+
+// The observation phase of the first k + 1 points (0 to k)
+func firstPoints(k int) observation {
+	return func(i int) bool {
+		return i <= k
+	}
+}
+
+// The classic secretary rule, stops at the first new best point after the observation phase
+type secretaryRule struct {
+	observing observation
+}
+
+func NewSecretaryRule(k int) StoppingRule {
+	return secretaryRule{observing: firstPoints(k)}
+}
+
+func (s secretaryRule) Stop(i int, value, min float64) bool {
+	return !s.observing(i) && value < min
+}
+
+// Stops at a new best point after the observation phase with a probability that grows with the number of
+// new best points already declined (0.1 for the first one, 0.2 for the second one and so on)
+type probabilisticRule struct {
+	observing observation
+	r         *rand.Rand
+	// The number of new best points seen after the observation phase
+	candidates int
+}
+
+func NewProbabilisticRule(k int, r *rand.Rand) StoppingRule {
+	return &probabilisticRule{observing: firstPoints(k), r: r}
+}
+
+func (p *probabilisticRule) Stop(i int, value, min float64) bool {
+	if p.observing(i) || value >= min {
+		return false
+	}
+	accept := p.r.Float64() < 0.1+(0.1*float64(p.candidates))
+	p.candidates++
+	return accept
+}
+
+// Stops at the first point after the observation phase that ranks among the best K points seen so far
+// (K = 1 is the classic secretary rule)
+type kSecretaryRule struct {
+	observing observation
+	K         int
+	// The values seen so far, sorted
+	values []float64
+}
+
+func NewKSecretaryRule(k, K int) StoppingRule {
+	return newKSecretaryRule(firstPoints(k), K)
+}
+
+func newKSecretaryRule(observing observation, K int) StoppingRule {
+	if K < 1 {
+		panic(fmt.Errorf("invalid rank %d, it should be at least 1", K))
+	}
+	return &kSecretaryRule{observing: observing, K: K}
+}
+
+func (s *kSecretaryRule) Stop(i int, value, min float64) bool {
+	if math.IsNaN(value) {
+		return false
+	}
+	rank := sort.SearchFloat64s(s.values, value)
+	s.values = append(s.values, 0)
+	copy(s.values[rank+1:], s.values[rank:])
+	s.values[rank] = value
+	return !s.observing(i) && rank < s.K
+}
+
+// Stops when the best value did not improve for a number of points
+type patienceRule struct {
+	patience int
+	// The number of points since the last improvement
+	since int
+}
+
+func NewPatienceRule(patience int) StoppingRule {
+	if patience < 1 {
+		panic(fmt.Errorf("invalid patience %d, it should be at least 1", patience))
+	}
+	return &patienceRule{patience: patience}
+}
+
+func (p *patienceRule) Stop(i int, value, min float64) bool {
+	if value < min {
+		p.since = 0
+		return false
+	}
+	p.since++
+	return p.since >= p.patience
+}
+
+// Stops at the first point that reaches the target value
+type targetRule struct {
+	target float64
+}
+
+func NewTargetRule(target float64) StoppingRule {
+	return targetRule{target: target}
+}
+
+func (t targetRule) Stop(i int, value, min float64) bool {
+	return value <= t.target
+}
+
+// A new stopping rule as set in vargs["stoppingRule"], either a StoppingRuleFactory or the name of a rule
+// (Secretary, Probabilistic, KSecretary, Patience or Target, the default is Probabilistic)
+// observing tells the points of the observation phase and r decides the probabilistic stops
+func newStoppingRule(vargs map[string]interface{}, observing observation, r *rand.Rand) StoppingRule {
+	if factory, ok := vargs["stoppingRule"].(StoppingRuleFactory); ok {
+		return factory()
+	}
+	if _, ok := vargs["stoppingRule"].(StoppingRule); ok {
+		panic("A stopping rule keeps the state of a search, please pass a StoppingRuleFactory instead!")
+	}
+	switch stringArg(vargs, "stoppingRule", "Probabilistic") {
+	case "Secretary":
+		return secretaryRule{observing: observing}
+	case "Probabilistic":
+		return &probabilisticRule{observing: observing, r: r}
+	case "KSecretary":
+		return newKSecretaryRule(observing, intArg(vargs, "secretaryK", 1))
+	case "Patience":
+		return NewPatienceRule(intArg(vargs, "patience", DefaultPatience))
+	case "Target":
+		target, ok := vargs["target"].(float64)
+		if !ok {
+			panic("Missing target value! Please specify a target!")
+		}
+		return NewTargetRule(target)
+	}
+	panic(fmt.Errorf("unknown stopping rule %v", vargs["stoppingRule"]))
+}
+
+// The vargs for the minimization of the negated function, the target value (if any) is negated too
+func negatedTarget(vargs map[string]interface{}) map[string]interface{} {
+	target, ok := vargs["target"].(float64)
+	if !ok {
+		return vargs
+	}
+	args := functions.CopyArgs(vargs)
+	args["target"] = -target
+	return args
+}
@@ -0,0 +1,369 @@
+package core_test
+
+import (
+	"errors"
+	"github.com/acflorea/goptim/core"
+	"github.com/acflorea/goptim/functions"
+	"github.com/acflorea/goptim/generators"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// (x - 1)^2 + (y + 2)^2
+func quadratic(point functions.MultidimensionalPoint, vargs map[string]interface{}) (float64, error) {
+	x, y := point.Values["x"].(float64), point.Values["y"].(float64)
+	return (x-1)*(x-1) + (y+2)*(y+2), nil
+}
+
+func newGenerator(pointsNo int) generators.Generator {
+	restrictions := []generators.GenerationStrategy{
+		generators.NewUniform("x", -5, 5),
+		generators.NewUniform("y", -5, 5),
+	}
+	return generators.NewRandom(restrictions, nil, []float64{1, 1}, generators.FixedProbabilities, false, 100,
+		pointsNo, 10, 1, generators.SeqSplit, 42)
+}
+
+func Test_Refinement(t *testing.T) {
+
+	_, _, min, _, _, phase := core.Minimize(quadratic, map[string]interface{}{}, newGenerator(200), 10, 200, 0, 42, false)
+	if phase != core.RandomPhase {
+		t.Error("Without refinement the optimum comes from the random phase", phase)
+	}
+
+	vargs := map[string]interface{}{"refinement": 0.5}
+	index, _, rmin, _, optimNo, phase := core.Minimize(quadratic, vargs, newGenerator(200), 10, 200, 0, 42, false)
+	if optimNo == 0 {
+		t.Fatal("The stopping rule should have been met")
+	}
+	if phase != core.RefinementPhase || rmin >= min || rmin > 1e-6 {
+		t.Error("The refinement should improve the optimum", phase, min, rmin)
+	}
+	if index >= 200 {
+		t.Error("The refinement should use only part of the remaining attempts", index)
+	}
+
+}
+
+// The quadratic with an error that vanishes at the full budget
+func budgetedQuadratic(point functions.MultidimensionalPoint, budget float64, vargs map[string]interface{}) (float64, error) {
+	value, err := quadratic(point, vargs)
+	x := point.Values["x"].(float64)
+	return value + (1-budget)*math.Sin(5*x), err
+}
+
+func Test_SuccessiveHalving(t *testing.T) {
+
+	evaluations, p, min, cost := core.SuccessiveHalving(budgetedQuadratic, map[string]interface{}{},
+		newGenerator(100), 27, 1.0/27, 3, 0)
+	if evaluations != 27+9+3+1 || math.Abs(cost-4) > 1e-9 {
+		t.Error("The bracket should evaluate 27, 9, 3 and 1 points", evaluations, cost)
+	}
+	if value, _ := quadratic(p, nil); value != min {
+		t.Error("The result should be evaluated at the full budget", value, min)
+	}
+
+}
+
+func Test_Hyperband(t *testing.T) {
+
+	evaluations, p, min, cost := core.Hyperband(budgetedQuadratic, map[string]interface{}{},
+		newGenerator(1000), 40, 1.0/27, 3, 0)
+	if cost > 40 {
+		t.Error("The search should fit in the budget", cost)
+	}
+	if evaluations <= 40 {
+		t.Error("The search should evaluate more points than full evaluations", evaluations)
+	}
+	if value, _ := quadratic(p, nil); value != min || min > 0.5 {
+		t.Error("The search should find a point close to the optimum", p.PrettyPrint(), min)
+	}
+
+}
+
+// The quadratic, approached over 10 steps that report the intermediate values
+func steppedQuadratic(point functions.MultidimensionalPoint, vargs map[string]interface{}) (float64, error) {
+	value, _ := quadratic(point, vargs)
+	intermediate := 0.0
+	for step := 0; step < 10; step++ {
+		intermediate = value + float64(10-step)
+		if functions.Report(vargs, step, intermediate) {
+			return intermediate, functions.ErrPruned
+		}
+	}
+	return value, nil
+}
+
+func Test_Pruning(t *testing.T) {
+
+	pruner := core.NewMedianPruner(1, 5)
+	vargs := map[string]interface{}{"pruner": pruner}
+	_, p, min, gmin, _, _ := core.Minimize(steppedQuadratic, vargs, newGenerator(200), 10, 200, 0, 42, true)
+	if pruner.Pruned() < 50 || pruner.Pruned() >= 200 {
+		t.Error("About half of the trials should be pruned", pruner.Pruned())
+	}
+	if value, _ := quadratic(p, nil); value != min || gmin > min {
+		t.Error("Pruned trials should not be reported as the optimum", p.PrettyPrint(), min, gmin)
+	}
+
+	_, _, max, _, _, _ := core.Maximize(functions.Negate(steppedQuadratic), map[string]interface{}{"pruner": "Median", "pruningWarmup": 1},
+		newGenerator(200), 10, 0, 200, 0, 42, true)
+	if max != -min {
+		t.Error("The values reported by negated functions should be negated too", max, min)
+	}
+
+}
+
+// x^2 and (x - 2)^2, the Pareto front is [0, 2]
+func twoParabolas(point functions.MultidimensionalPoint, vargs map[string]interface{}) ([]float64, error) {
+	x := point.Values["x"].(float64)
+	return []float64{x * x, (x - 2) * (x - 2)}, nil
+}
+
+func Test_MultiObjective(t *testing.T) {
+
+	restrictions := []generators.GenerationStrategy{
+		generators.NewUniform("x", -5, 5),
+		generators.NewUniform("y", -5, 5),
+	}
+	searches := map[string]generators.Generator{
+		"Random": generators.NewRandom(restrictions, nil, []float64{1, 1}, generators.FixedProbabilities, false, 100,
+			500, 10, 1, generators.SeqSplit, 42),
+		"NSGA2": generators.NewNSGA2(restrictions, nil, 500, 1, 42),
+	}
+	for name, generator := range searches {
+		front := core.MinimizeMulti(twoParabolas, map[string]interface{}{}, generator, 500, 0)
+		if len(front) < 10 {
+			t.Error(name, "The front should have many points", len(front))
+		}
+		for _, point := range front {
+			if x := point.Point.Values["x"].(float64); x < -0.1 || x > 2.1 {
+				t.Error(name, "The front should be close to [0, 2]", x, point.Values)
+			}
+			for _, other := range front {
+				if functions.Dominates(other.Values, point.Values) {
+					t.Error(name, "The front points should not dominate each other", other.Values, point.Values)
+				}
+			}
+		}
+	}
+
+	front := core.MaximizeMulti(func(point functions.MultidimensionalPoint, vargs map[string]interface{}) ([]float64, error) {
+		values, err := twoParabolas(point, vargs)
+		return []float64{-values[0], -values[1]}, err
+	}, map[string]interface{}{}, generators.NewNSGA2(restrictions, nil, 200, 1, 42), 200, 0)
+	for _, point := range front {
+		if point.Values[0] > 0 || point.Values[1] > 0 {
+			t.Error("The values of the maximized objectives should be reported as they are", point.Values)
+		}
+	}
+
+}
+
+func Test_StoppingRules(t *testing.T) {
+
+	values := []float64{5, 3, 4, 6, 3.5, 1, 2}
+	rules := map[string]struct {
+		rule core.StoppingRule
+		// the index of the point the rule stops at
+		stop int
+	}{
+		"Secretary":  {core.NewSecretaryRule(2), 5},
+		"KSecretary": {core.NewKSecretaryRule(2, 2), 4},
+		"Patience":   {core.NewPatienceRule(2), 3},
+		"Target":     {core.NewTargetRule(3), 1},
+	}
+	for name, test := range rules {
+		stop, min := -1, math.MaxFloat64
+		for i, value := range values {
+			if test.rule.Stop(i, value, min) {
+				stop = i
+				break
+			}
+			min = math.Min(min, value)
+		}
+		if stop != test.stop {
+			t.Error(name, "Unexpected stop", stop, test.stop)
+		}
+	}
+
+	vargs := map[string]interface{}{"stoppingRule": "Target", "target": 0.5}
+	index, _, min, _, optimNo, _ := core.Minimize(quadratic, vargs, newGenerator(1000), 10, 1000, 0, 42, false)
+	if optimNo == 0 || min > 0.5 || index >= 999 {
+		t.Error("The search should stop at the target", index, min)
+	}
+
+	vargs = map[string]interface{}{"stoppingRule": "Target", "target": -0.5}
+	_, _, max, _, optimNo, _ := core.Maximize(functions.Negate(quadratic), vargs, newGenerator(1000), 10, 0, 1000, 0, 42,
+		false)
+	if optimNo == 0 || max < -0.5 {
+		t.Error("The target should be in the units of the maximized function", max)
+	}
+
+	// each search starts with a rule of its own
+	var factory core.StoppingRuleFactory = func() core.StoppingRule { return core.NewPatienceRule(5) }
+	vargs = map[string]interface{}{"stoppingRule": factory}
+	first, _, _, _, _, _ := core.Minimize(quadratic, vargs, newGenerator(1000), 10, 1000, 0, 42, false)
+	second, _, _, _, _, _ := core.Minimize(quadratic, vargs, newGenerator(1000), 10, 1000, 0, 42, false)
+	if first != second {
+		t.Error("The searches should not share the state of the rule", first, second)
+	}
+
+}
+
+func slowQuadratic(point functions.MultidimensionalPoint, vargs map[string]interface{}) (float64, error) {
+	time.Sleep(time.Millisecond)
+	return quadratic(point, vargs)
+}
+
+func Test_Budget(t *testing.T) {
+
+	budget := core.NewBudget(0, 50*time.Millisecond, 1)
+	vargs := map[string]interface{}{"budget": budget, "stoppingRule": "Patience", "patience": 1000}
+	_, _, min, _, _, _ := core.Minimize(slowQuadratic, vargs, newGenerator(1000), 10, 1000, 0, 42, true)
+	if !budget.Exhausted() || budget.Evaluations() >= 1000 {
+		t.Error("The search should stop when the budget runs out", budget.Evaluations())
+	}
+	if min == math.MaxFloat64 {
+		t.Error("The search should report the best point found before the budget ran out")
+	}
+
+	// the observation phase is a share of the cost, no point is an optimum before it ends
+	budget = core.NewBudget(0, time.Hour, 1)
+	vargs = map[string]interface{}{"budget": budget, "stoppingRule": "Secretary"}
+	_, _, _, _, optimNo, _ := core.Minimize(quadratic, vargs, newGenerator(100), 10, 100, 0, 42, true)
+	if optimNo != 0 {
+		t.Error("No point should leave the observation phase", optimNo)
+	}
+
+	// the evaluations of the refinement are trials, as without a budget
+	calls := 0
+	counting := func(point functions.MultidimensionalPoint, vargs map[string]interface{}) (float64, error) {
+		calls++
+		return quadratic(point, vargs)
+	}
+	budget = core.NewBudget(0, time.Hour, 1)
+	vargs = map[string]interface{}{"budget": budget, "refinement": 0.5, "stoppingRule": "Patience", "patience": 20}
+	index, _, _, _, _, phase := core.Minimize(counting, vargs, newGenerator(200), 10, 200, 0, 42, false)
+	if phase != core.RefinementPhase || budget.Evaluations() != calls || index+1 != calls {
+		t.Error("The refinement should count as trials", phase, budget.Evaluations(), index, calls)
+	}
+
+}
+
+func Test_Checkpoint(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "checkpoint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "run")
+
+	restrictions := []generators.GenerationStrategy{
+		generators.NewUniform("x", -5, 5),
+		generators.NewUniform("y", -5, 5),
+	}
+
+	// the run is interrupted in the middle of the second experiment
+	evaluations := 0
+	f := func(point functions.MultidimensionalPoint, vargs map[string]interface{}) (float64, error) {
+		evaluations++
+		if content, err := ioutil.ReadFile(file); err == nil && evaluations == 150 {
+			ioutil.WriteFile(file+".interrupted", content, 0644)
+		}
+		value, err := quadratic(point, vargs)
+		return -value, err
+	}
+	optimize := func(seed int64, vargs map[string]interface{}) map[string]interface{} {
+		vargs["checkpoint"] = file
+		vargs["checkpointInterval"] = time.Nanosecond
+		return core.Optimize(3, restrictions, nil, []float64{1, 1}, false, 100, 100, 100, 1, generators.SeqSplit,
+			generators.Random, seed, f, true, vargs)
+	}
+
+	results := optimize(42, map[string]interface{}{})
+	total := evaluations
+
+	if err := os.Rename(file+".interrupted", file); err != nil {
+		t.Fatal(err)
+	}
+	evaluations = 0
+	// the seed of the resumed run is the one of the checkpoint
+	resumed := optimize(7, map[string]interface{}{"resume": true})
+
+	if evaluations != total-149 {
+		t.Error("The recorded trials should not be evaluated again", evaluations, total-149)
+	}
+	for _, key := range []string{"avg", "std", "earlyStopPercent", "matchPercent"} {
+		if results[key] != resumed[key] {
+			t.Error("The resumed run should end like the original one", key, results[key], resumed[key])
+		}
+	}
+
+}
+
+func Test_Infeasible(t *testing.T) {
+
+	restrictions := []generators.GenerationStrategy{
+		generators.NewUniform("x", -5, 5),
+		generators.NewUniform("y", -5, 5),
+	}
+	constraint, err := generators.ParseConstraint("x > 10")
+	if err != nil {
+		t.Fatal(err)
+	}
+	evaluations := 0
+	f := func(point functions.MultidimensionalPoint, vargs map[string]interface{}) (float64, error) {
+		evaluations++
+		return quadratic(point, vargs)
+	}
+
+	// the empty feasible region is reported before the workers start
+	results := core.Optimize(1, restrictions, []generators.Constraint{constraint}, []float64{1, 1}, false, 100, 100,
+		100, 2, generators.SeqSplit, generators.Random, 42, f, true, map[string]interface{}{})
+	if err, _ := results["error"].(error); !errors.Is(err, generators.ErrInfeasible) || evaluations != 0 {
+		t.Error("The run should report the infeasible search space", results["error"], evaluations)
+	}
+
+}
+
+func Test_UnknownNames(t *testing.T) {
+
+	restrictions := []generators.GenerationStrategy{
+		generators.NewUniform("x", -5, 5),
+		generators.NewUniform("y", -5, 5),
+	}
+	optimize := func(sampler generators.Sampler, vargs map[string]interface{}) (err interface{}) {
+		defer func() {
+			err = recover()
+		}()
+		core.Optimize(1, restrictions, nil, []float64{1, 1}, false, 100, 10, 10, 1, generators.SeqSplit, sampler, 42,
+			quadratic, true, vargs)
+		return nil
+	}
+
+	// the misspelled names are rejected instead of falling back to the first option
+	invalid := []struct {
+		sampler generators.Sampler
+		name    string
+	}{
+		{generators.Random, "adaptation"},
+		{generators.GaussianProcess, "acquisition"},
+		{generators.Annealing, "cooling"},
+		{generators.DifferentialEvolution, "deStrategy"},
+	}
+	for _, args := range invalid {
+		if optimize(args.sampler, map[string]interface{}{args.name: "Unknown"}) == nil {
+			t.Error("An unknown name should be rejected", args.name)
+		}
+	}
+	if err := optimize(generators.Random, map[string]interface{}{"adaptation": "Fixed"}); err != nil {
+		t.Error("A known name should be accepted", err)
+	}
+
+}
@@ -30,6 +30,12 @@ type Sample struct {
 	Value      float64
 	GValue     float64
 	FullSearch bool
+	// The point was found by a local search around the best random point
+	Refined bool
+	// The number of trials stopped before completion
+	Pruned int
+	// The non-dominated points of a multi-objective search
+	Front []ParetoPoint
 }
 
 // Prints a point in a friendly way
@@ -59,6 +65,41 @@ func (p *MultidimensionalPoint) PrettyPrint() (desc string) {
 // A type alias for a function taking a variable number of parameters and returning a float
 type NumericalFunction func(point MultidimensionalPoint, vargs map[string]interface{}) (float64, error)
 
+// A function with several objectives, returns the value of each of them
+type MultiObjectiveFunction func(point MultidimensionalPoint, vargs map[string]interface{}) ([]float64, error)
+
+// Receives the intermediate values of a long running function
+type Reporter interface {
+	// Reports the value of the function at a step (an epoch, an iteration...)
+	// Returns true if the function should stop and return ErrPruned
+	Report(step int, value float64) bool
+}
+
+// Returned (along with the last intermediate value) by the functions stopped by their Reporter
+var ErrPruned = errors.New("trial pruned")
+
+// Reports an intermediate value to the Reporter in vargs["reporter"], if any
+// Returns true if the function should stop
+func Report(vargs map[string]interface{}, step int, value float64) bool {
+	if reporter, ok := vargs["reporter"].(Reporter); ok {
+		return reporter.Report(step, value)
+	}
+	return false
+}
+
+// A Reporter that negates the values
+type negatedReporter struct {
+	reporter Reporter
+}
+
+func (r negatedReporter) Report(step int, value float64) bool {
+	return r.reporter.Report(step, -value)
+}
+
+// A function that can be evaluated with a fraction of its full budget (data, training epochs, folds...)
+// budget is in (0, 1], lower budgets are cheaper and less accurate
+type BudgetedFunction func(point MultidimensionalPoint, budget float64, vargs map[string]interface{}) (float64, error)
+
 // Constant function
 func F_constant(_ MultidimensionalPoint, vargs map[string]interface{}) (float64, error) {
 	return 10, nil
@@ -124,9 +165,53 @@ func F_sombrero(p MultidimensionalPoint, vargs map[string]interface{}) (float64,
 	}
 }
 
+// Returns a shallow copy of vargs
+func CopyArgs(vargs map[string]interface{}) map[string]interface{} {
+	args := make(map[string]interface{}, len(vargs))
+	for key, value := range vargs {
+		args[key] = value
+	}
+	return args
+}
+
+// Returns a copy of vargs with the values of the point added
+// (values of dimensions missing from the point, e.g. inactive conditional ones, do not leak from previous calls)
+func WithPoint(vargs map[string]interface{}, p MultidimensionalPoint) map[string]interface{} {
+	args := CopyArgs(vargs)
+	for key, value := range p.Values {
+		args[key] = value
+	}
+	return args
+}
+
+// The negation of a function (intermediate values are negated too)
 func Negate(f NumericalFunction) NumericalFunction {
 	return func(x MultidimensionalPoint, vargs map[string]interface{}) (float64, error) {
+		if reporter, ok := vargs["reporter"].(Reporter); ok {
+			vargs = CopyArgs(vargs)
+			vargs["reporter"] = negatedReporter{reporter}
+		}
 		y, err := f(x, vargs)
 		return -y, err
 	}
 }
+
+// The negation of a function with a budget
+func NegateBudgeted(f BudgetedFunction) BudgetedFunction {
+	return func(x MultidimensionalPoint, budget float64, vargs map[string]interface{}) (float64, error) {
+		y, err := f(x, budget, vargs)
+		return -y, err
+	}
+}
+
+// The negation of a function with several objectives
+func NegateMulti(f MultiObjectiveFunction) MultiObjectiveFunction {
+	return func(x MultidimensionalPoint, vargs map[string]interface{}) ([]float64, error) {
+		y, err := f(x, vargs)
+		negated := make([]float64, len(y))
+		for i, value := range y {
+			negated[i] = -value
+		}
+		return negated, err
+	}
+}
@@ -1,6 +1,7 @@
 package functions
 
 import (
+	"bufio"
 	"fmt"
 	"os/exec"
 	"strconv"
@@ -26,9 +27,7 @@ func K7M(p MultidimensionalPoint, vargs map[string]interface{}) (float64, error)
 	}
 
 	// Add Values to vargs
-	for key, value := range p.Values {
-		vargs[key] = value
-	}
+	vargs = WithPoint(vargs, p)
 
 	// unixOptions = "w:d:b:c:e:m:"
 	// gnuOptions = ["max_breadth=", "max_depth=", "attr_b=", "attr_c=", "edge_cost=", "movement_factor="]
@@ -44,15 +43,37 @@ func K7M(p MultidimensionalPoint, vargs map[string]interface{}) (float64, error)
 
 	cmd := exec.Command(command, params...)
 
-	result, err := cmd.Output()
+	// The numeric lines of the output are the progress of the optimizer, the last one is the target
+	target := 0.0
 
+	output, err := cmd.StdoutPipe()
+	if err == nil {
+		err = cmd.Start()
+	}
 	if err != nil {
 		fmt.Println(err)
+		fmt.Println(target)
+		return -target, nil
+	}
+	step := 0
+	scanner := bufio.NewScanner(output)
+	for scanner.Scan() {
+		value, err := strconv.ParseFloat(strings.TrimSpace(scanner.Text()), 64)
+		if err != nil {
+			continue
+		}
+		target = value
+		if Report(vargs, step, -target) {
+			cmd.Process.Kill()
+			cmd.Wait()
+			return -target, ErrPruned
+		}
+		step++
 	}
 
-	// target
-	rs := strings.Split(string(result), "\n")
-	target, _ := strconv.ParseFloat(string(rs[len(rs)-1]), 64)
+	if err := cmd.Wait(); err != nil {
+		fmt.Println(err)
+	}
 
 	fmt.Println(target)
 
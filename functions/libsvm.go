@@ -4,15 +4,18 @@ import (
 	"github.com/acflorea/libsvm-go"
 	"fmt"
 	"encoding/json"
+	"io/ioutil"
+	"math"
+	"os"
+	"strings"
+	"time"
 )
 
 // LIBSVM optimization through crossvalidation
 func LIBSVM_optim(p MultidimensionalPoint, vargs map[string]interface{}) (float64, error) {
 
 	// Add Values to vargs
-	for key, value := range p.Values {
-		vargs[key] = value
-	}
+	vargs = WithPoint(vargs, p)
 
 	fileName, ok := vargs["fileName"].(string)
 	if !ok {
@@ -33,6 +36,78 @@ func LIBSVM_optim(p MultidimensionalPoint, vargs map[string]interface{}) (float6
 	return accuracy, nil
 }
 
+// LIBSVM optimization of both the crossvalidation accuracy and the time it takes
+// Returns the accuracy and the negated duration in seconds (both are maximized)
+func LIBSVM_timed(p MultidimensionalPoint, vargs map[string]interface{}) ([]float64, error) {
+	start := time.Now()
+	accuracy, err := LIBSVM_optim(p, vargs)
+	return []float64{accuracy, -time.Since(start).Seconds()}, err
+}
+
+// LIBSVM optimization through crossvalidation on a subsample of the data
+// The budget is the fraction of the lines of the input file that are used
+func LIBSVM_budgeted(p MultidimensionalPoint, budget float64, vargs map[string]interface{}) (float64, error) {
+
+	if budget >= 1 {
+		return LIBSVM_optim(p, vargs)
+	}
+
+	// Add Values to vargs
+	vargs = WithPoint(vargs, p)
+
+	fileName, ok := vargs["fileName"].(string)
+	if !ok {
+		panic("Missing input data! Please specify a fileName!")
+	}
+
+	sampleName, err := subsample(fileName, budget)
+	if err != nil {
+		panic(err)
+	}
+	defer os.Remove(sampleName)
+
+	param := libSvm.NewParameter() // Create a parameter object with default values
+
+	// Create a problem specification from the training data and parameter attributes
+	problem, err := libSvm.NewProblem(sampleName, param)
+
+	if err != nil {
+		panic(err)
+	}
+
+	accuracy, _, _ := CrossV(problem, vargs)
+
+	return accuracy, nil
+}
+
+// Writes a fraction of the lines of the file, evenly spread (so the classes keep their proportions
+// even if the file is sorted), to a temporary file
+func subsample(fileName string, fraction float64) (string, error) {
+
+	content, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return "", err
+	}
+
+	sample, err := ioutil.TempFile("", "goptim-sample")
+	if err != nil {
+		return "", err
+	}
+	defer sample.Close()
+
+	lines := strings.Split(strings.TrimRight(string(content), "\n"), "\n")
+	for i, line := range lines {
+		// the line that takes the count of selected lines to the next integer
+		if math.Floor(float64(i+1)*fraction) > math.Floor(float64(i)*fraction) {
+			if _, err := sample.WriteString(line + "\n"); err != nil {
+				return "", err
+			}
+		}
+	}
+
+	return sample.Name(), nil
+}
+
 func CrossV(problem *libSvm.Problem, vargs map[string]interface{}) (accuracy float64, all, TPs int) {
 
 	quietMode := true
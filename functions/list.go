@@ -11,3 +11,13 @@ var Functions = map[string]NumericalFunction{
 	"SparkIt":      SparkIt,
 	"K7M":          K7M,
 }
+
+// Map with the functions that accept a budget, by name
+var BudgetedFunctions = map[string]BudgetedFunction{
+	"LIBSVM_optim": LIBSVM_budgeted,
+}
+
+// Map with the functions that have several objectives, by name
+var MultiObjectiveFunctions = map[string]MultiObjectiveFunction{
+	"LIBSVM_optim": LIBSVM_timed,
+}
@@ -0,0 +1,48 @@
+package functions
+
+// A point and the values of all the objectives in it
+type ParetoPoint struct {
+	Point  MultidimensionalPoint
+	Values []float64
+}
+
+// Checks if the values a dominate the values b (lower is better): a is nowhere worse and somewhere better
+func Dominates(a, b []float64) bool {
+	better := false
+	for i := range a {
+		if a[i] > b[i] {
+			return false
+		}
+		if a[i] < b[i] {
+			better = true
+		}
+	}
+	return better
+}
+
+// The points that are not dominated by any other point, in their original order
+// (of the points with the same values only the first one is kept)
+func NonDominated(points []ParetoPoint) (front []ParetoPoint) {
+	for i, candidate := range points {
+		dominated := false
+		for j, other := range points {
+			if Dominates(other.Values, candidate.Values) || j < i && equal(other.Values, candidate.Values) {
+				dominated = true
+				break
+			}
+		}
+		if !dominated {
+			front = append(front, candidate)
+		}
+	}
+	return
+}
+
+func equal(a, b []float64) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
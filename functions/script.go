@@ -30,9 +30,7 @@ func Script(p MultidimensionalPoint, vargs map[string]interface{}) (float64, err
 	}
 
 	// Add Values to vargs
-	for key, value := range p.Values {
-		vargs[key] = value
-	}
+	vargs = WithPoint(vargs, p)
 
 	_kernel, ok := vargs["kernel"].(int)
 	kernel := "linear"
@@ -1,6 +1,7 @@
 package functions
 
 import (
+	"bufio"
 	"os/exec"
 	"fmt"
 	"io/ioutil"
@@ -56,7 +57,31 @@ func SparkIt(p MultidimensionalPoint, vargs map[string]interface{}) (float64, er
 		sparkParams,
 		targetJar)
 
-	if err := cmd.Run(); err != nil {
+	// The output lines with an F measure (as in the results file) are the progress of the job
+	output, err := cmd.StdoutPipe()
+	if err == nil {
+		err = cmd.Start()
+	}
+	if err != nil {
+		fmt.Println("Error: ", err)
+		return 0.0, err
+	}
+	step := 0
+	scanner := bufio.NewScanner(output)
+	for scanner.Scan() {
+		f1Measure, ok := fMeasure(scanner.Text())
+		if !ok {
+			continue
+		}
+		if Report(vargs, step, f1Measure) {
+			cmd.Process.Kill()
+			cmd.Wait()
+			return f1Measure, ErrPruned
+		}
+		step++
+	}
+
+	if err := cmd.Wait(); err != nil {
 		fmt.Println("Error: ", err)
 		return 0.0, err
 	}
@@ -65,8 +90,18 @@ func SparkIt(p MultidimensionalPoint, vargs map[string]interface{}) (float64, er
 	resultsStr := string(dat)
 	fmt.Println(p.Values["categoryScalingFactor"].(string), p.Values["productScalingFactor"].(string), string(dat))
 
-	f1str := strings.TrimPrefix(strings.Split(resultsStr, " ")[2], "F:")
-	f1Measure, _ := strconv.ParseFloat(f1str, 64)
+	f1Measure, _ := fMeasure(resultsStr)
 
 	return f1Measure, nil
 }
+
+// The F measure of a line of results (the field prefixed by F:)
+func fMeasure(line string) (float64, bool) {
+	for _, field := range strings.Fields(line) {
+		if strings.HasPrefix(field, "F:") {
+			f1Measure, err := strconv.ParseFloat(strings.TrimPrefix(field, "F:"), 64)
+			return f1Measure, err == nil
+		}
+	}
+	return 0.0, false
+}
@@ -75,3 +75,23 @@ func TestF_x_square(t *testing.T) {
 	}
 
 }
+
+func TestNonDominated(t *testing.T) {
+
+	points := []functions.ParetoPoint{
+		{Values: []float64{1, 4}},
+		{Values: []float64{2, 2}},
+		{Values: []float64{3, 3}},
+		{Values: []float64{4, 1}},
+		{Values: []float64{2, 2}},
+	}
+
+	front := functions.NonDominated(points)
+	if len(front) != 3 || front[0].Values[0] != 1 || front[1].Values[0] != 2 || front[2].Values[0] != 4 {
+		t.Error("Unexpected front", front)
+	}
+
+	if functions.Dominates([]float64{2, 2}, []float64{2, 2}) {
+		t.Error("Equal values do not dominate each other")
+	}
+}
@@ -0,0 +1,104 @@
+package generators
+
+import (
+	"github.com/acflorea/goptim/functions"
+	"math"
+)
+
+// The factor (in log scale) a probability to change decreases with after a failure of the 1/5th success rule,
+// a success increases it by four times as much so the probabilities settle where one step in five succeeds
+var AdaptationStep = 0.05
+
+// The weight of the exploration term of the bandit (UCB1) credit of the dimensions
+var BanditExploration = 1.0
+
+// The smallest probability to change of the adaptive generators, so no dimension freezes
+var MinProbabilityToChange = 0.05
+
+// The credit of the dimensions of a worker
+type credit struct {
+	// The dimensions changed by the last point (nil if there is nothing to learn from it)
+	changed []bool
+	// The number of points that changed each dimension and the number of them that were an improvement
+	trials, successes []int
+}
+
+// Checks if the adaptation learns from the points of the search phase
+func (a Adaptation) adaptive() bool {
+	return a == OneFifthRule || a == BanditProbabilities
+}
+
+// Records which dimensions of the point differ from the centroid it was generated from
+func (g randomGenerator) recordChanges(w int, point, centroid functions.MultidimensionalPoint) {
+	changed := make([]bool, g.dimensionsNo)
+	for dimIdx, restriction := range g.restrictions {
+		value, ok := point.Values[restriction.Label]
+		changed[dimIdx] = ok && value != centroid.Values[restriction.Label]
+	}
+	g.credit[w].changed = changed
+}
+
+// Updates the probabilities to change of the worker from the outcome of its last point
+// (a success if it is the best point so far)
+func (g randomGenerator) adapt(w int, success bool) {
+
+	c := g.credit[w]
+	probabilities := g.probabilities[w]
+
+	total := 0
+	for dimIdx, changed := range c.changed {
+		if changed {
+			c.trials[dimIdx]++
+			if success {
+				c.successes[dimIdx]++
+			}
+		}
+		total += c.trials[dimIdx]
+	}
+
+	switch g.adaptation {
+	case OneFifthRule:
+		for dimIdx, changed := range c.changed {
+			if !changed {
+				continue
+			}
+			if success {
+				probabilities[dimIdx] *= math.Exp(4 * AdaptationStep)
+			} else {
+				probabilities[dimIdx] *= math.Exp(-AdaptationStep)
+			}
+			probabilities[dimIdx] = math.Max(MinProbabilityToChange, math.Min(1, probabilities[dimIdx]))
+		}
+		if g.adjustSingleValue {
+			normalizeProbabilities(probabilities, true)
+		}
+	case BanditProbabilities:
+		// UCB1, the dimensions that never changed get the highest credit
+		for dimIdx := range probabilities {
+			probabilities[dimIdx] = math.Inf(1)
+			if c.trials[dimIdx] > 0 {
+				trials := float64(c.trials[dimIdx])
+				probabilities[dimIdx] = float64(c.successes[dimIdx])/trials +
+					BanditExploration*math.Sqrt(math.Log(float64(total))/trials)
+			}
+		}
+		top := 0.0
+		for _, p := range probabilities {
+			if !math.IsInf(p, 1) {
+				top = math.Max(top, p)
+			}
+		}
+		for dimIdx, p := range probabilities {
+			if math.IsInf(p, 1) || top == 0 {
+				probabilities[dimIdx] = 1
+			} else {
+				probabilities[dimIdx] = math.Max(MinProbabilityToChange, p/top)
+			}
+		}
+		if g.adjustSingleValue {
+			normalizeProbabilities(probabilities, true)
+		}
+	}
+
+	c.changed = nil
+}
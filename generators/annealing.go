@@ -0,0 +1,248 @@
+package generators
+
+import (
+	"github.com/acflorea/goptim/functions"
+	"math"
+	"math/rand"
+)
+
+// The initial temperature of simulated annealing used by default
+var DefaultTemperature = 1.0
+
+// The initial standard deviation of the steps (relative to the range of the dimensions),
+// it decreases with the square root of the temperature
+var AnnealingStep = 0.1
+
+// Simulated annealing generator
+// Each worker walks from its current point to a neighbour, a worse neighbour becomes the current point
+// with probability exp(-delta / (T * s)) where s is the standard deviation of the values seen by the worker
+// (so the temperature does not depend on the scale of the target function)
+type annealingGenerator struct {
+	// The generation strategy on each dimension
+	restrictions []GenerationStrategy
+	// Constraints between dimensions, each generated point satisfies all of them
+	constraints []Constraint
+	// The temperature schedule, starting from temperature
+	schedule    CoolingSchedule
+	temperature float64
+	// How many points to generate per worker
+	pointsNo int
+	// The current point of each worker and its value
+	current      []functions.MultidimensionalPoint
+	currentValue []float64
+	// The count, mean and sum of squared deviations of the values seen by each worker
+	count    []int
+	mean, m2 []float64
+	rngs     []*rand.Rand
+	told     []int
+	index    []int
+}
+
+// Generates points with simulated annealing, the first point of each worker is random (or close to the centroid)
+// Continuous dimensions (and the logarithmic integers) take normally distributed steps, integers and quantized
+// values move by a normally distributed number of values (at least one), numeric choices to a neighbouring choice
+// and the other discrete ones to a random choice.
+// Each dimension changes with probability 1/n (at least one changes).
+func NewAnnealing(restrictions []GenerationStrategy, constraints []Constraint, schedule CoolingSchedule,
+	temperature float64, pointsNo, cores int, seed int64) Generator {
+
+	rngs := make([]*rand.Rand, cores)
+	for w := range rngs {
+		rngs[w] = rand.New(rand.NewSource(seed + int64(w)*1000003))
+	}
+
+	return annealingGenerator{
+		restrictions: restrictions,
+		constraints:  constraints,
+		schedule:     schedule,
+		temperature:  temperature,
+		pointsNo:     pointsNo,
+		current:      make([]functions.MultidimensionalPoint, cores),
+		currentValue: make([]float64, cores),
+		count:        make([]int, cores),
+		mean:         make([]float64, cores),
+		m2:           make([]float64, cores),
+		rngs:         rngs,
+		told:         make([]int, cores),
+		index:        make([]int, cores),
+	}
+}
+
+func (g annealingGenerator) Next(w int, initialState GeneratorState) (point functions.MultidimensionalPoint, state GeneratorState) {
+
+	state = initialState
+	r := g.rngs[w]
+
+	for ; g.told[w] < len(state.Output) && g.told[w] < len(state.GeneratedPoints); g.told[w]++ {
+		value := state.value(g.told[w])
+		if math.IsNaN(value) || math.IsInf(value, 0) {
+			continue
+		}
+
+		// Welford's online variance
+		g.count[w]++
+		delta := value - g.mean[w]
+		g.mean[w] += delta / float64(g.count[w])
+		g.m2[w] += delta * (value - g.mean[w])
+
+		if g.current[w].Values == nil || g.accept(w, value-g.currentValue[w], r) {
+			g.current[w], g.currentValue[w] = state.GeneratedPoints[g.told[w]], value
+		}
+	}
+
+	// before the first result the walk starts around the centroid, if there is one
+	from := g.current[w]
+	if from.Values == nil {
+		from = state.Centroid
+	}
+	if len(from.Values) == 0 {
+		point = randomPoint(g.restrictions, g.constraints, r)
+	} else {
+		point = sampleFeasible(g.constraints, func() functions.MultidimensionalPoint {
+			return g.neighbour(from, g.index[w], r)
+		})
+	}
+
+	state.GeneratedPoints = append(state.GeneratedPoints, point)
+
+	g.index[w]++
+
+	return
+}
+
+// Metropolis criterion
+func (g annealingGenerator) accept(w int, delta float64, r *rand.Rand) bool {
+	if delta <= 0 {
+		return true
+	}
+	scale := 1.0
+	if g.count[w] > 1 && g.m2[w] > 0 {
+		scale = math.Sqrt(g.m2[w] / float64(g.count[w]-1))
+	}
+	temperature := g.temperatureAt(g.index[w])
+	return temperature > 0 && r.Float64() < math.Exp(-delta/(temperature*scale))
+}
+
+// The temperature after k points
+func (g annealingGenerator) temperatureAt(k int) float64 {
+	n := float64(g.pointsNo)
+	switch g.schedule {
+	case LinearCooling:
+		return g.temperature * math.Max(0, 1-float64(k)/n)
+	case LogarithmicCooling:
+		return g.temperature * math.Log(2) / math.Log(float64(k)+2)
+	}
+	return g.temperature * math.Pow(1e-3, float64(k)/n)
+}
+
+// A random neighbour of the point, k is the number of points generated so far
+func (g annealingGenerator) neighbour(point functions.MultidimensionalPoint, k int,
+	r *rand.Rand) functions.MultidimensionalPoint {
+
+	active := 0
+	for _, restriction := range g.restrictions {
+		if _, ok := point.Values[restriction.Label]; ok {
+			active++
+		}
+	}
+	forced := r.Intn(int(math.Max(1, float64(active))))
+	// the steps get smaller as the temperature decreases
+	size := AnnealingStep * math.Sqrt(g.temperatureAt(k)/g.temperature)
+
+	values := make(map[string]interface{})
+	activeIdx := 0
+	for _, restriction := range g.restrictions {
+		if !restriction.isActive(values) {
+			continue
+		}
+		value, ok := point.Values[restriction.Label]
+		switch {
+		case !ok:
+			// the dimension was inactive in the point
+			value = restriction.valueAt(r.Float64())
+		case activeIdx == forced || r.Float64() < 1/float64(active):
+			value = restriction.step(value, size, r)
+		}
+		if ok {
+			activeIdx++
+		}
+		values[restriction.Label] = value
+	}
+
+	return functions.MultidimensionalPoint{Values: values}
+}
+
+// A value close to the given one, size is the standard deviation of the steps relative to the range of values
+func (s GenerationStrategy) step(value interface{}, size float64, r *rand.Rand) interface{} {
+
+	direction := 1
+	if r.Float64() < 0.5 {
+		direction = -1
+	}
+	// a step of at least one value, larger on wide ranges
+	distance := func(valuesNo int) int {
+		return int(math.Max(1, math.Round(math.Abs(size*float64(valuesNo)*r.NormFloat64()))))
+	}
+
+	switch s.Distribution {
+	case IntUniform:
+		a, b := int(s.LowerBound), int(s.UpperBound)
+		next := value.(int) + direction*distance(b-a+1)
+		// bounce back from the bounds
+		if next < a {
+			next = 2*a - next
+		}
+		if next > b {
+			next = 2*b - next
+		}
+		return int(math.Max(float64(a), math.Min(float64(b), float64(next))))
+	case QUniform:
+		n := int(math.Floor((s.UpperBound-s.LowerBound)/s.Step+1e-9)) + 1
+		k := int(math.Round((value.(float64)-s.LowerBound)/s.Step)) + direction*distance(n)
+		if k < 0 {
+			k = -k
+		}
+		if k >= n {
+			k = 2*(n-1) - k
+		}
+		return gridValue(s.LowerBound, s.Step, int(math.Max(0, math.Min(float64(n-1), float64(k)))))
+	case Discrete:
+		ordered := s.ordered()
+		current := choiceIndex(ordered, ordered.unitOf(value))
+		n := len(ordered.Choices)
+		if n == 1 {
+			return value
+		}
+		next := r.Intn(n - 1)
+		if next >= current {
+			next++
+		}
+		if ordinal(ordered) {
+			next = current + direction
+			if next < 0 || next >= n {
+				next = current - direction
+			}
+		}
+		return ordered.Choices[next].Value
+	}
+
+	return s.valueAt(reflect(s.unitOf(value) + size*r.NormFloat64()))
+}
+
+// Checks if all the choices are numbers (and so ordered by value)
+func ordinal(ordered GenerationStrategy) bool {
+	for _, choice := range ordered.Choices {
+		if _, err := toFloat64(choice.Value); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+func (g annealingGenerator) HasNext(w int) bool {
+	return g.index[w] < g.pointsNo
+}
+
+func (g annealingGenerator) Improvement(state GeneratorState) bool {
+	return newBest(state)
+}
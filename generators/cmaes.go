@@ -0,0 +1,269 @@
+package generators
+
+import (
+	"math"
+	"math/rand"
+)
+
+// The initial step size of CMA-ES, relative to the unit hypercube
+var CMAESInitialSigma = 0.3
+
+// Covariance Matrix Adaptation Evolution Strategy (N. Hansen, The CMA Evolution Strategy: A Tutorial)
+// The search runs on the positions of the values in the cumulative distribution functions, so the discrete
+// and integer dimensions are rounded to the closest value. The coordinates are reflected into the unit hypercube.
+type cmaes struct {
+	// The number of dimensions
+	n int
+	// Whether the population doubles at each restart
+	ipop bool
+	// The number of restarts so far
+	restarts int
+
+	// Strategy parameters
+	lambda, mu                   int
+	weights                      []float64
+	mueff, cc, cs, c1, cmu, damp float64
+	// The expected norm of a N(0, I) distributed vector
+	chiN float64
+
+	// The state of the search
+	mean  []float64
+	sigma float64
+	// The covariance matrix and its decomposition C = B * D^2 * B^T
+	c, b [][]float64
+	d    []float64
+	// The evolution paths
+	pc, ps []float64
+	// The number of generations since the last restart
+	generations int
+	// The best value since the last restart and the generation that found it
+	best           float64
+	bestGeneration int
+}
+
+// Generates points with CMA-ES
+// When the search stagnates it restarts from a random mean, with a population twice as large when ipop is set
+func NewCMAES(restrictions []GenerationStrategy, constraints []Constraint, ipop bool,
+	pointsNo, cores int, seed int64) Generator {
+
+	generator := newPopulationGenerator(restrictions, constraints, nil, pointsNo, cores, seed)
+	evolution := &cmaes{n: len(restrictions), ipop: ipop}
+	evolution.start(generator.r)
+	generator.evolution = evolution
+	return generator
+}
+
+// (Re)starts the search
+func (e *cmaes) start(r *rand.Rand) {
+
+	n := float64(e.n)
+
+	e.lambda = 4 + int(3*math.Log(n))
+	if e.ipop {
+		e.lambda <<= uint(e.restarts)
+	}
+	e.mu = e.lambda / 2
+
+	e.weights = make([]float64, e.mu)
+	sum, sum2 := 0.0, 0.0
+	for i := range e.weights {
+		e.weights[i] = math.Log(float64(e.mu)+0.5) - math.Log(float64(i+1))
+		sum += e.weights[i]
+	}
+	for i := range e.weights {
+		e.weights[i] /= sum
+		sum2 += e.weights[i] * e.weights[i]
+	}
+	e.mueff = 1 / sum2
+
+	e.cc = (4 + e.mueff/n) / (n + 4 + 2*e.mueff/n)
+	e.cs = (e.mueff + 2) / (n + e.mueff + 5)
+	e.c1 = 2 / ((n+1.3)*(n+1.3) + e.mueff)
+	e.cmu = math.Min(1-e.c1, 2*(e.mueff-2+1/e.mueff)/((n+2)*(n+2)+e.mueff))
+	e.damp = 1 + 2*math.Max(0, math.Sqrt((e.mueff-1)/(n+1))-1) + e.cs
+	e.chiN = math.Sqrt(n) * (1 - 1/(4*n) + 1/(21*n*n))
+
+	e.mean = randomCoordinates(e.n, r)
+	e.sigma = CMAESInitialSigma
+	e.c, e.b = identity(e.n), identity(e.n)
+	e.d = make([]float64, e.n)
+	for i := range e.d {
+		e.d[i] = 1
+	}
+	e.pc, e.ps = make([]float64, e.n), make([]float64, e.n)
+	e.generations = 0
+	e.best, e.bestGeneration = math.Inf(1), 0
+}
+
+func (e *cmaes) populationSize() int {
+	return e.lambda
+}
+
+// mean + sigma * B * D * z with z ~ N(0, I)
+func (e *cmaes) sample(index int, r *rand.Rand) []float64 {
+	z := make([]float64, e.n)
+	for i := range z {
+		z[i] = e.d[i] * r.NormFloat64()
+	}
+	x := make([]float64, e.n)
+	for i := range x {
+		y := 0.0
+		for j := range z {
+			y += e.b[i][j] * z[j]
+		}
+		x[i] = reflect(e.mean[i] + e.sigma*y)
+	}
+	return x
+}
+
+func (e *cmaes) tell(trials []trial, r *rand.Rand) {
+
+	n := float64(e.n)
+	e.generations++
+
+	old := e.mean
+	e.mean = make([]float64, e.n)
+	for k := 0; k < e.mu; k++ {
+		for i := range e.mean {
+			e.mean[i] += e.weights[k] * trials[k].coordinates[i]
+		}
+	}
+	yw := make([]float64, e.n)
+	for i := range yw {
+		yw[i] = (e.mean[i] - old[i]) / e.sigma
+	}
+
+	// ps = (1 - cs) * ps + sqrt(cs * (2 - cs) * mueff) * C^(-1/2) * yw
+	bty := make([]float64, e.n)
+	for j := range bty {
+		for i := range yw {
+			bty[j] += e.b[i][j] * yw[i]
+		}
+		bty[j] /= e.d[j]
+	}
+	norm := 0.0
+	for i := range e.ps {
+		invSqrt := 0.0
+		for j := range bty {
+			invSqrt += e.b[i][j] * bty[j]
+		}
+		e.ps[i] = (1-e.cs)*e.ps[i] + math.Sqrt(e.cs*(2-e.cs)*e.mueff)*invSqrt
+		norm += e.ps[i] * e.ps[i]
+	}
+	norm = math.Sqrt(norm)
+
+	hsig := 0.0
+	if norm/math.Sqrt(1-math.Pow(1-e.cs, 2*float64(e.generations)))/e.chiN < 1.4+2/(n+1) {
+		hsig = 1
+	}
+	for i := range e.pc {
+		e.pc[i] = (1-e.cc)*e.pc[i] + hsig*math.Sqrt(e.cc*(2-e.cc)*e.mueff)*yw[i]
+	}
+
+	// rank one and rank mu updates
+	for i := range e.c {
+		for j := 0; j <= i; j++ {
+			rankMu := 0.0
+			for k := 0; k < e.mu; k++ {
+				rankMu += e.weights[k] * (trials[k].coordinates[i] - old[i]) * (trials[k].coordinates[j] - old[j])
+			}
+			rankMu /= e.sigma * e.sigma
+			e.c[i][j] = (1-e.c1-e.cmu)*e.c[i][j] +
+				e.c1*(e.pc[i]*e.pc[j]+(1-hsig)*e.cc*(2-e.cc)*e.c[i][j]) + e.cmu*rankMu
+			e.c[j][i] = e.c[i][j]
+		}
+	}
+
+	e.sigma *= math.Exp(e.cs / e.damp * (norm/e.chiN - 1))
+
+	var eigenvalues []float64
+	eigenvalues, e.b = symmetricEigen(e.c)
+	for i, eigenvalue := range eigenvalues {
+		e.d[i] = math.Sqrt(math.Max(eigenvalue, 1e-20))
+	}
+
+	if trials[0].value < e.best {
+		e.best, e.bestGeneration = trials[0].value, e.generations
+	}
+	if e.stagnates(trials) {
+		e.restarts++
+		e.start(r)
+	}
+}
+
+// The termination criteria that trigger a restart
+func (e *cmaes) stagnates(trials []trial) bool {
+	maxD, minD := 0.0, math.Inf(1)
+	for i := range e.d {
+		maxD, minD = math.Max(maxD, e.d[i]), math.Min(minD, e.d[i])
+	}
+	// no improvement for a while
+	patience := 10 + int(math.Ceil(30*float64(e.n)/float64(e.lambda)))
+	return e.sigma*maxD < 1e-12 ||
+		// the covariance matrix is ill conditioned
+		maxD > 1e7*minD ||
+		// the values are flat
+		trials[0].value == trials[int(math.Ceil(0.7*float64(len(trials))))-1].value ||
+		e.generations-e.bestGeneration > patience
+}
+
+func identity(n int) [][]float64 {
+	m := make([][]float64, n)
+	for i := range m {
+		m[i] = make([]float64, n)
+		m[i][i] = 1
+	}
+	return m
+}
+
+// The eigenvalues and the eigenvectors (the columns of the matrix) of a symmetric matrix (Jacobi method)
+func symmetricEigen(a [][]float64) ([]float64, [][]float64) {
+
+	n := len(a)
+	m := make([][]float64, n)
+	for i := range a {
+		m[i] = append([]float64(nil), a[i]...)
+	}
+	v := identity(n)
+
+	for sweep := 0; sweep < 100; sweep++ {
+		off := 0.0
+		for i := 0; i < n; i++ {
+			for j := i + 1; j < n; j++ {
+				off += m[i][j] * m[i][j]
+			}
+		}
+		if off < 1e-30 {
+			break
+		}
+		for p := 0; p < n; p++ {
+			for q := p + 1; q < n; q++ {
+				if m[p][q] == 0 {
+					continue
+				}
+				theta := (m[q][q] - m[p][p]) / (2 * m[p][q])
+				t := math.Copysign(1, theta) / (math.Abs(theta) + math.Sqrt(theta*theta+1))
+				c := 1 / math.Sqrt(t*t+1)
+				s := t * c
+				for k := 0; k < n; k++ {
+					mkp, mkq := m[k][p], m[k][q]
+					m[k][p], m[k][q] = c*mkp-s*mkq, s*mkp+c*mkq
+				}
+				for k := 0; k < n; k++ {
+					mpk, mqk := m[p][k], m[q][k]
+					m[p][k], m[q][k] = c*mpk-s*mqk, s*mpk+c*mqk
+				}
+				for k := 0; k < n; k++ {
+					vkp, vkq := v[k][p], v[k][q]
+					v[k][p], v[k][q] = c*vkp-s*vkq, s*vkp+c*vkq
+				}
+			}
+		}
+	}
+
+	eigenvalues := make([]float64, n)
+	for i := range eigenvalues {
+		eigenvalues[i] = m[i][i]
+	}
+	return eigenvalues, v
+}
@@ -0,0 +1,296 @@
+package generators
+
+import (
+	"errors"
+	"fmt"
+	"github.com/acflorea/goptim/functions"
+	"math/rand"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// A predicate over the values of a point (e.g. max_depth >= max_breadth)
+// Generators only emit points for which all the constraints hold
+type Constraint func(point functions.MultidimensionalPoint) bool
+
+// How many candidates are generated for a point before deciding the feasible region is (almost) empty
+var MaxConstraintAttempts = 10000
+
+// Raised (as a panic, the generators have no other way to report it) when no feasible point can be found
+var ErrInfeasible = errors.New("the feasible region of the search space looks empty")
+
+// Checks if the point satisfies all the constraints
+func Feasible(point functions.MultidimensionalPoint, constraints []Constraint) bool {
+	for _, constraint := range constraints {
+		if !constraint(point) {
+			return false
+		}
+	}
+	return true
+}
+
+// Checks, by sampling up to MaxConstraintAttempts random points of the space, that the feasible region is not empty
+// The error wraps ErrInfeasible, it lets the callers report the problem before the generators run into it
+func CheckFeasible(restrictions []GenerationStrategy, constraints []Constraint, seed int64) error {
+	if len(constraints) == 0 {
+		return nil
+	}
+	r := rand.New(rand.NewSource(seed))
+	coordinates := make([]float64, len(restrictions))
+	for attempt := 0; attempt < MaxConstraintAttempts; attempt++ {
+		for i := range coordinates {
+			coordinates[i] = r.Float64()
+		}
+		if Feasible(unitToPoint(restrictions, coordinates), constraints) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: no feasible point found in %d attempts", ErrInfeasible, MaxConstraintAttempts)
+}
+
+// Builds a constraint out of a comparison between two arithmetic expressions
+// The expressions use numbers, dimension labels, parentheses and the + - * / operators,
+// the comparison is one of < <= > >= == !=
+// Eg: "max_depth >= max_breadth", "attr_b * attr_c < 0"
+// A constraint that refers a dimension missing from the point (e.g. an inactive conditional one) holds,
+// one that refers a non numeric value does not.
+func ParseConstraint(expression string) (Constraint, error) {
+	constraint, _, err := parseConstraint(expression)
+	return constraint, err
+}
+
+// Parses the constraint and also returns the labels it refers
+func parseConstraint(expression string) (Constraint, []string, error) {
+
+	tokens, err := tokenize(expression)
+	if err != nil {
+		return nil, nil, fmt.Errorf("constraint %q: %v", expression, err)
+	}
+
+	p := &parser{tokens: tokens}
+	left, err := p.expression()
+	if err != nil {
+		return nil, nil, fmt.Errorf("constraint %q: %v", expression, err)
+	}
+	operator := p.next()
+	compare, ok := comparisons[operator]
+	if !ok {
+		return nil, nil, fmt.Errorf("constraint %q: expected a comparison but got %q", expression, operator)
+	}
+	right, err := p.expression()
+	if err != nil {
+		return nil, nil, fmt.Errorf("constraint %q: %v", expression, err)
+	}
+	if p.pos < len(p.tokens) {
+		return nil, nil, fmt.Errorf("constraint %q: unexpected %q", expression, p.tokens[p.pos])
+	}
+
+	return func(point functions.MultidimensionalPoint) bool {
+		l, errL := left(point.Values)
+		r, errR := right(point.Values)
+		if errL == errMissing || errR == errMissing {
+			return true
+		}
+		if errL != nil || errR != nil {
+			return false
+		}
+		return compare(l, r)
+	}, p.labels, nil
+}
+
+var comparisons = map[string]func(a, b float64) bool{
+	"<":  func(a, b float64) bool { return a < b },
+	"<=": func(a, b float64) bool { return a <= b },
+	">":  func(a, b float64) bool { return a > b },
+	">=": func(a, b float64) bool { return a >= b },
+	"==": func(a, b float64) bool { return a == b },
+	"!=": func(a, b float64) bool { return a != b },
+}
+
+var errMissing = errors.New("missing value")
+var errNotNumeric = errors.New("not a numeric value")
+
+// An arithmetic expression evaluated against the values of a point
+type term func(values map[string]interface{}) (float64, error)
+
+// Splits an expression into numbers, labels, operators and parentheses
+func tokenize(expression string) (tokens []string, err error) {
+	runes := []rune(expression)
+	for i := 0; i < len(runes); {
+		c := runes[i]
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case strings.ContainsRune("<>=!", c):
+			if i+1 < len(runes) && runes[i+1] == '=' {
+				tokens = append(tokens, string(runes[i:i+2]))
+				i += 2
+			} else if c == '<' || c == '>' {
+				tokens = append(tokens, string(c))
+				i++
+			} else {
+				return nil, fmt.Errorf("unexpected %q", string(c))
+			}
+		case strings.ContainsRune("+-*/()", c):
+			tokens = append(tokens, string(c))
+			i++
+		case unicode.IsDigit(c) || c == '.' || unicode.IsLetter(c) || c == '_':
+			j := i
+			for j < len(runes) && (unicode.IsDigit(runes[j]) || unicode.IsLetter(runes[j]) ||
+				runes[j] == '_' || runes[j] == '.') {
+				j++
+			}
+			tokens = append(tokens, string(runes[i:j]))
+			i = j
+		default:
+			return nil, fmt.Errorf("unexpected %q", string(c))
+		}
+	}
+	return
+}
+
+// A recursive descent parser for arithmetic expressions
+type parser struct {
+	tokens []string
+	pos    int
+	// the labels found so far
+	labels []string
+}
+
+func (p *parser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *parser) next() string {
+	token := p.peek()
+	p.pos++
+	return token
+}
+
+// expression := product (('+' | '-') product)*
+func (p *parser) expression() (term, error) {
+	left, err := p.product()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "+" || p.peek() == "-" {
+		operator := p.next()
+		right, err := p.product()
+		if err != nil {
+			return nil, err
+		}
+		left = combine(left, right, operator)
+	}
+	return left, nil
+}
+
+// product := factor (('*' | '/') factor)*
+func (p *parser) product() (term, error) {
+	left, err := p.factor()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "*" || p.peek() == "/" {
+		operator := p.next()
+		right, err := p.factor()
+		if err != nil {
+			return nil, err
+		}
+		left = combine(left, right, operator)
+	}
+	return left, nil
+}
+
+// factor := number | label | '-' factor | '(' expression ')'
+func (p *parser) factor() (term, error) {
+	token := p.next()
+	switch {
+	case token == "":
+		return nil, errors.New("unexpected end of expression")
+	case token == "-":
+		operand, err := p.factor()
+		if err != nil {
+			return nil, err
+		}
+		return func(values map[string]interface{}) (float64, error) {
+			value, err := operand(values)
+			return -value, err
+		}, nil
+	case token == "(":
+		inner, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+		if p.next() != ")" {
+			return nil, errors.New("missing )")
+		}
+		return inner, nil
+	case unicode.IsDigit(rune(token[0])) || token[0] == '.':
+		number, err := strconv.ParseFloat(token, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", token)
+		}
+		return func(map[string]interface{}) (float64, error) {
+			return number, nil
+		}, nil
+	case unicode.IsLetter(rune(token[0])) || token[0] == '_':
+		p.labels = append(p.labels, token)
+		return func(values map[string]interface{}) (float64, error) {
+			value, ok := values[token]
+			if !ok {
+				return 0, errMissing
+			}
+			return toFloat64(value)
+		}, nil
+	}
+	return nil, fmt.Errorf("unexpected %q", token)
+}
+
+func combine(left, right term, operator string) term {
+	return func(values map[string]interface{}) (float64, error) {
+		l, err := left(values)
+		if err != nil {
+			return 0, err
+		}
+		r, err := right(values)
+		if err != nil {
+			return 0, err
+		}
+		switch operator {
+		case "+":
+			return l + r, nil
+		case "-":
+			return l - r, nil
+		case "*":
+			return l * r, nil
+		}
+		return l / r, nil
+	}
+}
+
+// Converts the numeric values generated by the strategies to float64
+func toFloat64(value interface{}) (float64, error) {
+	switch v := value.(type) {
+	case float64:
+		return v, nil
+	case int:
+		return float64(v), nil
+	}
+	return 0, errNotNumeric
+}
+
+// Samples points until one satisfies all the constraints
+func sampleFeasible(constraints []Constraint, sample func() functions.MultidimensionalPoint) functions.MultidimensionalPoint {
+	point := sample()
+	for attempt := 1; !Feasible(point, constraints); attempt++ {
+		if attempt >= MaxConstraintAttempts {
+			panic(fmt.Errorf("%w: no feasible point found in %d attempts", ErrInfeasible, attempt))
+		}
+		point = sample()
+	}
+	return point
+}
@@ -0,0 +1,214 @@
+package generators
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// The differential weight F of differential evolution
+var DEWeight = 0.8
+
+// The crossover probability CR of differential evolution
+var DECrossover = 0.9
+
+// The probability that two genetic algorithm parents are recombined (their copies are used otherwise)
+var GACrossover = 0.9
+
+// The standard deviation of the genetic algorithm mutation of continuous genes
+var GAMutationStep = 0.1
+
+// The individuals of a population based search and their values, best first
+type individuals struct {
+	coordinates [][]float64
+	values      []float64
+}
+
+// Replaces the individuals with the best ones of the population and the trials
+func (p *individuals) merge(trials []trial, size int) {
+	for _, t := range trials {
+		p.coordinates = append(p.coordinates, t.coordinates)
+		p.values = append(p.values, t.value)
+	}
+	order := make([]int, len(p.values))
+	for idx := range order {
+		order[idx] = idx
+	}
+	sort.SliceStable(order, func(i, j int) bool { return p.values[order[i]] < p.values[order[j]] })
+	if len(order) > size {
+		order = order[:size]
+	}
+	coordinates, values := make([][]float64, len(order)), make([]float64, len(order))
+	for rank, idx := range order {
+		coordinates[rank], values[rank] = p.coordinates[idx], p.values[idx]
+	}
+	p.coordinates, p.values = coordinates, values
+}
+
+// Differential evolution (R. Storn and K. Price)
+// Each trial is a mutant crossed over with its target, it replaces the target if it is better
+// (the extra trials of a generation have no target, they replace the worst individual if they are better)
+type differentialEvolution struct {
+	// The number of dimensions
+	n        int
+	strategy DEStrategy
+	size     int
+	// The population, empty until the first (random) generation is evaluated
+	population individuals
+}
+
+// Generates points with differential evolution, the population has 10 individuals per dimension
+func NewDifferentialEvolution(restrictions []GenerationStrategy, constraints []Constraint, strategy DEStrategy,
+	pointsNo, cores int, seed int64) Generator {
+
+	evolution := &differentialEvolution{
+		n:        len(restrictions),
+		strategy: strategy,
+		size:     int(math.Max(4, float64(10*len(restrictions)))),
+	}
+	return newPopulationGenerator(restrictions, constraints, evolution, pointsNo, cores, seed)
+}
+
+func (e *differentialEvolution) populationSize() int {
+	return e.size
+}
+
+func (e *differentialEvolution) sample(index int, r *rand.Rand) []float64 {
+
+	if len(e.population.values) == 0 {
+		// the first generation is random
+		return randomCoordinates(e.n, r)
+	}
+
+	target := index
+	if target < 0 {
+		target = r.Intn(e.size)
+	}
+
+	// distinct individuals, different from the target
+	picked := map[int]bool{target: true}
+	pick := func() []float64 {
+		idx := r.Intn(e.size)
+		for picked[idx] {
+			idx = r.Intn(e.size)
+		}
+		picked[idx] = true
+		return e.population.coordinates[idx]
+	}
+
+	var base []float64
+	if e.strategy == Best1Bin {
+		// the population is sorted, the best individual comes first
+		base = e.population.coordinates[0]
+	} else {
+		base = pick()
+	}
+	a, b := pick(), pick()
+
+	// binomial crossover, at least one coordinate comes from the mutant
+	trial := append([]float64(nil), e.population.coordinates[target]...)
+	forced := r.Intn(e.n)
+	for i := range trial {
+		if i == forced || r.Float64() < DECrossover {
+			trial[i] = reflect(base[i] + DEWeight*(a[i]-b[i]))
+		}
+	}
+	return trial
+}
+
+func (e *differentialEvolution) tell(trials []trial, r *rand.Rand) {
+
+	if len(e.population.values) == 0 {
+		e.population.merge(trials, e.size)
+		return
+	}
+
+	for _, t := range trials {
+		target := t.index
+		if target < 0 {
+			target = e.size - 1
+		}
+		if t.value <= e.population.values[target] {
+			e.population.coordinates[target], e.population.values[target] = t.coordinates, t.value
+		}
+	}
+	// keep the best individual first (and the worst last)
+	e.population.merge(nil, e.size)
+}
+
+// Genetic algorithm
+// The offspring of two parents selected by tournament get the genes of either parent (uniform crossover),
+// each gene mutating with probability 1/n: continuous genes take a normally distributed step,
+// discrete ones a random value. The best individuals among parents and offspring survive.
+type geneticAlgorithm struct {
+	restrictions []GenerationStrategy
+	size         int
+	// The population, empty until the first (random) generation is evaluated
+	population individuals
+}
+
+// Generates points with a genetic algorithm, the population has 10 individuals per dimension (at least 10)
+func NewGeneticAlgorithm(restrictions []GenerationStrategy, constraints []Constraint,
+	pointsNo, cores int, seed int64) Generator {
+
+	evolution := &geneticAlgorithm{
+		restrictions: restrictions,
+		size:         int(math.Max(10, float64(10*len(restrictions)))),
+	}
+	return newPopulationGenerator(restrictions, constraints, evolution, pointsNo, cores, seed)
+}
+
+func (e *geneticAlgorithm) populationSize() int {
+	return e.size
+}
+
+func (e *geneticAlgorithm) sample(index int, r *rand.Rand) []float64 {
+
+	n := len(e.restrictions)
+	if len(e.population.values) == 0 {
+		// the first generation is random
+		return randomCoordinates(n, r)
+	}
+
+	return e.offspring(e.tournament(r), e.tournament(r), r)
+}
+
+// A child of the parents, recombined and mutated
+func (e *geneticAlgorithm) offspring(mother, father []float64, r *rand.Rand) []float64 {
+
+	n := len(e.restrictions)
+	child := append([]float64(nil), mother...)
+	if r.Float64() < GACrossover {
+		for i := range child {
+			if r.Float64() < 0.5 {
+				child[i] = father[i]
+			}
+		}
+	}
+
+	for i, restriction := range e.restrictions {
+		if r.Float64() >= 1/float64(n) {
+			continue
+		}
+		if restriction.Distribution == Discrete {
+			child[i] = r.Float64()
+		} else {
+			child[i] = reflect(child[i] + GAMutationStep*r.NormFloat64())
+		}
+	}
+	return child
+}
+
+// Binary tournament, the better of two random individuals
+func (e *geneticAlgorithm) tournament(r *rand.Rand) []float64 {
+	a, b := r.Intn(len(e.population.values)), r.Intn(len(e.population.values))
+	// the population is sorted, the lower index wins
+	if b < a {
+		a = b
+	}
+	return e.population.coordinates[a]
+}
+
+func (e *geneticAlgorithm) tell(trials []trial, r *rand.Rand) {
+	e.population.merge(trials, e.size)
+}
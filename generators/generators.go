@@ -1,9 +1,11 @@
 package generators
 
 import (
+	"fmt"
 	"github.com/acflorea/goptim/functions"
 	"math"
 	"math/rand"
+	"sort"
 	"sync"
 	"time"
 )
@@ -15,7 +17,7 @@ func Float64(a, b float64, r *rand.Rand) (float64, float64) {
 	r = initGenerator(r)
 
 	original := r.Float64()
-	return original, a + (b-a)*original
+	return original, uniformAt(a, b, original)
 }
 
 // Generates a random value from an exponential distribution with rate lambda
@@ -23,8 +25,127 @@ func ExpFloat64(lambda float64, r *rand.Rand) (float64, float64) {
 	r = initGenerator(r)
 
 	original := r.Float64()
+	return original, exponentialAt(lambda, original)
+}
+
+// Generates a random value between a and b (a > 0) uniformly distributed on a logarithmic scale
+func LogFloat64(a, b float64, r *rand.Rand) (float64, float64) {
+	r = initGenerator(r)
+
+	original := r.Float64()
+	return original, logUniformAt(a, b, original)
+}
+
+// Generates a random integer between a and b (both included, a > 0) uniformly distributed on a logarithmic scale
+func LogInt(a, b int, r *rand.Rand) (float64, int) {
+	r = initGenerator(r)
+
+	original := r.Float64()
+	return original, logIntAt(a, b, original)
+}
+
+// Generates a uniform random integer between a and b (both included)
+func Int(a, b int, r *rand.Rand) (float64, int) {
+	r = initGenerator(r)
+
+	original := r.Float64()
+	return original, intAt(a, b, original)
+}
+
+// Generates a uniform random value from the grid a, a+q, a+2q... bounded by b (included)
+func QFloat64(a, b, q float64, r *rand.Rand) (float64, float64) {
+	r = initGenerator(r)
+
+	original := r.Float64()
+	return original, quantizedAt(a, b, q, original)
+}
+
+// Generates a random value from a normal distribution
+func NormFloat64(mean, stddev float64, r *rand.Rand) (float64, float64) {
+	r = initGenerator(r)
+
+	original := r.Float64()
+	return original, normalAt(mean, stddev, original)
+}
+
+// Generates a random value from a normal distribution truncated to [a, b]
+func TruncNormFloat64(mean, stddev, a, b float64, r *rand.Rand) (float64, float64) {
+	r = initGenerator(r)
+
+	original := r.Float64()
+	return original, truncNormalAt(mean, stddev, a, b, original)
+}
+
+// Generates a random value whose logarithm is normally distributed
+func LogNormFloat64(mean, stddev float64, r *rand.Rand) (float64, float64) {
+	original, value := NormFloat64(mean, stddev, r)
+	return original, math.Exp(value)
+}
+
+// The functions below map a value u from [0, 1) to the corresponding value of a distribution
+// (the inverse of the cumulative distribution function)
+
+func uniformAt(a, b, u float64) float64 {
+	return a + (b-a)*u
+}
+
+func exponentialAt(lambda, u float64) float64 {
 	// x = log(1-u)/(−λ)
-	return original, math.Log(1-original) / (-lambda)
+	return math.Log(1-u) / (-lambda)
+}
+
+func logUniformAt(a, b, u float64) float64 {
+	// x = exp(log(a) + (log(b)-log(a))*u)
+	return math.Exp(math.Log(a) + (math.Log(b)-math.Log(a))*u)
+}
+
+func logIntAt(a, b int, u float64) int {
+	value := logUniformAt(float64(a), float64(b+1), u)
+	// the upper bound is excluded, the flooring keeps the value in [a, b]
+	return int(math.Min(math.Floor(value), float64(b)))
+}
+
+func intAt(a, b int, u float64) int {
+	return a + int(math.Min(math.Floor(u*float64(b-a+1)), float64(b-a)))
+}
+
+func quantizedAt(a, b, q, u float64) float64 {
+	// number of points in the grid
+	n := int(math.Floor((b-a)/q+1e-9)) + 1
+	k := int(math.Min(math.Floor(u*float64(n)), float64(n-1)))
+	return gridValue(a, q, k)
+}
+
+func normalAt(mean, stddev, u float64) float64 {
+	return mean + stddev*normalQuantile(u)
+}
+
+func truncNormalAt(mean, stddev, a, b, u float64) float64 {
+	// sample uniformly between the cdf values of the bounds and map it back
+	pa, pb := normalCDF((a-mean)/stddev), normalCDF((b-mean)/stddev)
+	value := mean + stddev*normalQuantile(pa+(pb-pa)*u)
+	return math.Max(a, math.Min(b, value))
+}
+
+// The cumulative distribution function of the standard normal distribution
+func normalCDF(x float64) float64 {
+	return 0.5 * math.Erfc(-x/math.Sqrt2)
+}
+
+// The inverse of the standard normal cdf
+func normalQuantile(p float64) float64 {
+	// avoid infinite values at the ends of [0, 1)
+	p = math.Max(p, math.SmallestNonzeroFloat64)
+	return math.Sqrt2 * math.Erfinv(2*p-1)
+}
+
+// Returns the k-th value of the grid a, a+q, a+2q...
+func gridValue(a, q float64, k int) float64 {
+	if m := math.Round(1 / q); m > 1 && math.Abs(1/q-m) < 1e-9 && math.Abs(a*m-math.Round(a*m)) < 1e-9 {
+		// steps like 0.1 are not exactly representable, count in units of q to avoid rounding errors
+		return (math.Round(a*m) + float64(k)) / m
+	}
+	return a + float64(k)*q
 }
 
 func initGenerator(r *rand.Rand) *rand.Rand {
@@ -47,47 +168,286 @@ type GenerationStrategy struct {
 	Lambda float64
 	// Lower and Upper bounds for uniform distribution
 	LowerBound, UpperBound float64
+	// The step (quantum) for quantized distributions
+	Step float64
+	// Distribution specific parameters (e.g. mean and stddev for normal distributions)
+	Params map[string]float64
 	// Map of value->probability for discrete distribution
 	Values map[interface{}]float64
+	// The same values in a deterministic order, and their cumulative probabilities
+	Choices []DiscreteChoice
+	cdf     []float64
+	// If specified the dimension is generated only when the condition holds
+	Condition *Condition
+}
+
+// Activation condition of a (child) dimension
+// The dimension is active only when the Parent dimension is active and has one of the Values
+type Condition struct {
+	Parent string
+	Values []interface{}
+}
+
+// Makes the dimension conditional on the value of a parent dimension
+// The parent has to be defined before the dimension in the restrictions list
+// Eg: NewIntUniform("degree", 2, 5).When("kernel", 1) - degree is only generated for polynomial kernels
+func (s GenerationStrategy) When(parent string, values ...interface{}) GenerationStrategy {
+	s.Condition = &Condition{Parent: parent, Values: values}
+	return s
+}
+
+// Checks if the dimension is active given the values generated for the other dimensions
+func (s GenerationStrategy) isActive(values map[string]interface{}) bool {
+	if s.Condition == nil {
+		return true
+	}
+	parentValue, ok := values[s.Condition.Parent]
+	if !ok {
+		// the parent itself is not active
+		return false
+	}
+	for _, value := range s.Condition.Values {
+		if value == parentValue {
+			return true
+		}
+	}
+	return false
 }
 
 // Generates values uniform distributed between a and b
 func NewUniform(label string, a, b float64) GenerationStrategy {
 	return GenerationStrategy{
-		label, Uniform, 0.0, a, b, nil,
+		Label: label, Distribution: Uniform, LowerBound: a, UpperBound: b,
 	}
 }
 
 // Generates values exponentially distributed with parameter lambda
 func NewExponential(label string, lambda float64) GenerationStrategy {
 	return GenerationStrategy{
-		label, Exponential, lambda, 0.0, 0.0, nil,
+		Label: label, Distribution: Exponential, Lambda: lambda,
 	}
 }
 
-// Generates values exponentially distributed with parameter lambda
+// Generates values between a and b (a > 0) uniformly distributed on a logarithmic scale
+func NewLogUniform(label string, a, b float64) GenerationStrategy {
+	return GenerationStrategy{
+		Label: label, Distribution: LogUniform, LowerBound: a, UpperBound: b,
+	}
+}
+
+// Generates integer values between a and b (both included, a > 0) uniformly distributed on a logarithmic scale
+func NewLogInteger(label string, a, b int) GenerationStrategy {
+	return GenerationStrategy{
+		Label: label, Distribution: LogInteger, LowerBound: float64(a), UpperBound: float64(b),
+	}
+}
+
+// Generates integer values between a and b (both included) uniformly distributed
+func NewIntUniform(label string, a, b int) GenerationStrategy {
+	return GenerationStrategy{
+		Label: label, Distribution: IntUniform, LowerBound: float64(a), UpperBound: float64(b),
+	}
+}
+
+// Generates values uniformly distributed on the grid a, a+q, a+2q... bounded by b (included)
+func NewQUniform(label string, a, b, q float64) GenerationStrategy {
+	return GenerationStrategy{
+		Label: label, Distribution: QUniform, LowerBound: a, UpperBound: b, Step: q,
+	}
+}
+
+// Generates normally distributed values
+func NewNormal(label string, mean, stddev float64) GenerationStrategy {
+	return GenerationStrategy{
+		Label: label, Distribution: Normal,
+		Params: map[string]float64{"mean": mean, "stddev": stddev},
+	}
+}
+
+// Generates normally distributed values truncated to [a, b]
+func NewTruncatedNormal(label string, mean, stddev, a, b float64) GenerationStrategy {
+	return GenerationStrategy{
+		Label: label, Distribution: TruncatedNormal, LowerBound: a, UpperBound: b,
+		Params: map[string]float64{"mean": mean, "stddev": stddev},
+	}
+}
+
+// Generates values whose logarithm is normally distributed with the given mean and stddev
+func NewLogNormal(label string, mean, stddev float64) GenerationStrategy {
+	return GenerationStrategy{
+		Label: label, Distribution: LogNormal,
+		Params: map[string]float64{"mean": mean, "stddev": stddev},
+	}
+}
+
+// Retrieves a distribution specific parameter
+func (s GenerationStrategy) Param(name string, defaultValue float64) float64 {
+	if value, ok := s.Params[name]; ok {
+		return value
+	}
+	return defaultValue
+}
+
+// A value of a discrete distribution and its probability
+type DiscreteChoice struct {
+	Value       interface{}
+	Probability float64
+}
+
+// Generates values from the set of given values, each with its (normalized) probability
+// The values are sorted (numbers first, by value, then everything else by its string representation),
+// so the same random number always maps to the same value
 func NewDiscrete(label string, values map[interface{}]float64) GenerationStrategy {
 
+	choices := make([]DiscreteChoice, 0, len(values))
+	for key, value := range values {
+		choices = append(choices, DiscreteChoice{key, value})
+	}
+	sort.Slice(choices, func(i, j int) bool {
+		return lessValue(choices[i].Value, choices[j].Value)
+	})
+
+	return NewOrderedDiscrete(label, choices)
+}
+
+// Generates values from the list of choices, each with its (normalized) probability
+// The order of the choices is preserved
+func NewOrderedDiscrete(label string, choices []DiscreteChoice) GenerationStrategy {
+
 	// normalize the values so the sum gives one
 	sum := 0.0
-	for _, value := range values {
-		sum += value
+	for _, choice := range choices {
+		sum += choice.Probability
 	}
-	if sum == 1.0 {
-		return GenerationStrategy{
-			label, Discrete, 1.0, 0.0, 0.0, values,
-		}
-	} else {
-		factor := 1.0 / sum
-		nValues := make(map[interface{}]float64)
-		for key, value := range values {
-			nValues[key] = value * factor
-		}
-		return GenerationStrategy{
-			label, Discrete, 1.0, 0.0, 0.0, nValues,
+
+	nChoices := make([]DiscreteChoice, len(choices))
+	nValues := make(map[interface{}]float64)
+	cdf := make([]float64, len(choices))
+	cumulative := 0.0
+	for idx, choice := range choices {
+		nChoices[idx] = DiscreteChoice{choice.Value, choice.Probability / sum}
+		nValues[choice.Value] += choice.Probability / sum
+		cumulative += choice.Probability / sum
+		cdf[idx] = cumulative
+	}
+
+	return GenerationStrategy{
+		Label: label, Distribution: Discrete, Lambda: 1.0, Values: nValues, Choices: nChoices, cdf: cdf,
+	}
+}
+
+// Orders numbers before anything else and compares everything else by its string representation
+func lessValue(a, b interface{}) bool {
+	x, errA := toFloat64(a)
+	y, errB := toFloat64(b)
+	switch {
+	case errA == nil && errB == nil && x != y:
+		return x < y
+	case errA == nil && errB != nil:
+		return true
+	case errA != nil && errB == nil:
+		return false
+	}
+	// equal numbers of different types (e.g. 1 and 1.0) or non numeric values
+	sa, sb := fmt.Sprintf("%T %v", a, a), fmt.Sprintf("%T %v", b, b)
+	return sa < sb
+}
+
+// Picks the discrete value corresponding to the cumulative probability u
+func (s GenerationStrategy) choose(u float64) interface{} {
+	s = s.ordered()
+	idx := sort.SearchFloat64s(s.cdf, u)
+	if idx == len(s.cdf) {
+		// rounding errors in the last cumulative probability
+		idx--
+	}
+	return s.Choices[idx].Value
+}
+
+// Generates a value according to the strategy
+func (s GenerationStrategy) generate(r *rand.Rand) interface{} {
+	return s.valueAt(r.Float64())
+}
+
+// The discrete strategy with its choices ordered
+func (s GenerationStrategy) ordered() GenerationStrategy {
+	if len(s.cdf) == 0 {
+		// the strategy was not built with NewDiscrete
+		return NewDiscrete(s.Label, s.Values)
+	}
+	return s
+}
+
+// Maps a value u from [0, 1) to the value of the strategy's distribution with the same cumulative probability
+// (a uniform u gives values distributed according to the strategy)
+func (s GenerationStrategy) valueAt(u float64) (value interface{}) {
+	switch s.Distribution {
+	case Uniform:
+		value = uniformAt(s.LowerBound, s.UpperBound, u)
+	case Exponential:
+		value = exponentialAt(s.Lambda, u)
+	case LogUniform:
+		value = logUniformAt(s.LowerBound, s.UpperBound, u)
+	case LogInteger:
+		value = logIntAt(int(s.LowerBound), int(s.UpperBound), u)
+	case IntUniform:
+		value = intAt(int(s.LowerBound), int(s.UpperBound), u)
+	case QUniform:
+		value = quantizedAt(s.LowerBound, s.UpperBound, s.Step, u)
+	case Normal:
+		value = normalAt(s.Param("mean", 0.0), s.Param("stddev", 1.0), u)
+	case TruncatedNormal:
+		value = truncNormalAt(s.Param("mean", 0.0), s.Param("stddev", 1.0), s.LowerBound, s.UpperBound, u)
+	case LogNormal:
+		value = math.Exp(normalAt(s.Param("mean", 0.0), s.Param("stddev", 1.0), u))
+	case Discrete:
+		value = s.choose(u)
+	}
+	return
+}
+
+// The inverse of valueAt, maps a value of the distribution back to [0, 1)
+// (to the middle of the interval mapped to the value for discrete, integer and quantized distributions)
+func (s GenerationStrategy) unitOf(value interface{}) float64 {
+
+	if s.Distribution == Discrete {
+		s = s.ordered()
+		lower := 0.0
+		for idx, choice := range s.Choices {
+			if choice.Value == value {
+				return (lower + s.cdf[idx]) / 2
+			}
+			lower = s.cdf[idx]
 		}
+		return 0.5
 	}
 
+	x, _ := toFloat64(value)
+	a, b := s.LowerBound, s.UpperBound
+	switch s.Distribution {
+	case Uniform:
+		return (x - a) / (b - a)
+	case Exponential:
+		return 1 - math.Exp(-s.Lambda*x)
+	case LogUniform:
+		return math.Log(x/a) / math.Log(b/a)
+	case LogInteger:
+		return (math.Log(x/a) + math.Log((x+1)/a)) / 2 / math.Log((b+1)/a)
+	case IntUniform:
+		return (x - a + 0.5) / (b - a + 1)
+	case QUniform:
+		n := math.Floor((b-a)/s.Step+1e-9) + 1
+		return (math.Round((x-a)/s.Step) + 0.5) / n
+	case Normal:
+		return normalCDF((x - s.Param("mean", 0.0)) / s.Param("stddev", 1.0))
+	case TruncatedNormal:
+		mean, stddev := s.Param("mean", 0.0), s.Param("stddev", 1.0)
+		pa, pb := normalCDF((a-mean)/stddev), normalCDF((b-mean)/stddev)
+		return (normalCDF((x-mean)/stddev) - pa) / (pb - pa)
+	case LogNormal:
+		return normalCDF((math.Log(x) - s.Param("mean", 0.0)) / s.Param("stddev", 1.0))
+	}
+	return 0.5
 }
 
 type GeneratorState struct {
@@ -99,6 +459,33 @@ type GeneratorState struct {
 	Output []float64
 	// centroid
 	Centroid functions.MultidimensionalPoint
+	// indices (in Output) of the points whose evaluation was pruned, their output is the last intermediate value
+	// (the generators learn nothing from them)
+	Pruned []int
+	// values of all the objectives for those points (multi-objective functions only, Output has the first one)
+	Objectives [][]float64
+	// the probabilities to change each dimension used for those points (weighted random search only)
+	Probabilities [][]float64
+}
+
+// The output of the idx-th point, +Inf if its evaluation was pruned
+// (the last intermediate value of a pruned trial is not a value to learn from)
+func (state GeneratorState) value(idx int) float64 {
+	for _, pruned := range state.Pruned {
+		if pruned == idx {
+			return math.Inf(1)
+		}
+	}
+	return state.Output[idx]
+}
+
+// The outputs of the points, +Inf for the pruned ones
+func (state GeneratorState) values() []float64 {
+	values := make([]float64, len(state.Output))
+	for idx := range values {
+		values[idx] = state.value(idx)
+	}
+	return values
 }
 
 type Generator interface {
@@ -114,11 +501,17 @@ type randomGenerator struct {
 	// The generation strategy on each dimension
 	// GenerationStrategies are considered in the order they are defined (1st strategy applies to 1st dimension etc)
 	restrictions []GenerationStrategy
+	// Constraints between dimensions, each generated point satisfies all of them
+	constraints []Constraint
 	// probability to change for each dimension
 	// the probability to change for each dimension
 	probabilityToChange []float64
-	// the probability to change for each dimension - reversed
-	reverse_probabilityToChange []float64
+	// the way the probabilities to change evolve
+	adaptation Adaptation
+	// the current probabilities to change of each worker
+	probabilities [][]float64
+	// the credit of the dimensions of each worker (adaptive generators)
+	credit []*credit
 	// change a single value per step
 	adjustSingleValue bool
 	// optimalSlicePercent - the slice of results that are considered in the optimal range
@@ -135,71 +528,46 @@ type randomGenerator struct {
 	index []int
 	// internal random generator(s)
 	rs []*rand.Rand
+	// the seed of the generator
+	seed int64
 }
 
+// Creates a (weighted) random generator
+// The generator is seeded with seed so runs can be reproduced
+// adaptation decides if the probabilities to change stay fixed or are learned from the points of each worker
 func NewRandom(restrictions []GenerationStrategy,
+	constraints []Constraint,
 	probabilityToChange []float64,
+	adaptation Adaptation,
 	adjustSingleValue bool,
 	optimalSlicePercent float64,
 	pointsNo int,
 	minPointsNo int,
 	cores int,
-	algorithm Algorithm) Generator {
-
-	if adjustSingleValue {
-		// adjust the probabilityToChange values to sum up to 1.0
-		// normalize the values so the sum gives one
-		sum := float64(0.0)
-		for _, value := range probabilityToChange {
-			sum += value
-		}
-		if sum != 1.0 {
-			factor := 1.0 / sum
-			for key, value := range probabilityToChange {
-				probabilityToChange[key] = value * factor
-			}
-		}
-	} else {
-		// make sure at least one value changes each step
-		factor := 0.0
-		for _, p := range probabilityToChange {
-			if p >= factor {
-				factor = p
-			}
-		}
-
-		for key, value := range probabilityToChange {
-			probabilityToChange[key] = value / factor
-		}
-	}
+	algorithm Algorithm,
+	seed int64) Generator {
 
-	// compute the reverse probabilityToChange
-	reverse_probabilityToChange := []float64{}
-	for _, value := range probabilityToChange {
-		reverse_probabilityToChange = append(reverse_probabilityToChange, 1.0-value)
-	}
-
-	// Init generator
-	now := time.Now().UnixNano()
+	normalizeProbabilities(probabilityToChange, adjustSingleValue)
 
+	// Init generator(s) - the same seed gives the same sequence of points
 	rs := make([]*rand.Rand, cores, cores)
 
 	switch algorithm {
 	case ManagerWorker:
 		// Same generator for all workers
-		source := rand.NewSource(now)
+		source := rand.NewSource(seed)
 		r := rand.New(source)
 		for i := 0; i < cores; i++ {
 			rs[i] = r
 		}
 	case Leapfrog:
 		for i := 0; i < cores; i++ {
-			source := rand.NewSource(now)
+			source := rand.NewSource(seed)
 			rs[i] = rand.New(source)
 		}
 	case SeqSplit:
 		for i := 0; i < cores; i++ {
-			source := rand.NewSource(now)
+			source := rand.NewSource(seed)
 			rs[i] = rand.New(source)
 			// Advance the generator
 			for j := 0; j < i*pointsNo/cores; j++ {
@@ -209,37 +577,119 @@ func NewRandom(restrictions []GenerationStrategy,
 	case Parametrization:
 		for i := 0; i < cores; i++ {
 			// Different seed meaning different sequences
-			source := rand.NewSource(now - int64(i))
+			source := rand.NewSource(seed - int64(i))
 			rs[i] = rand.New(source)
 		}
 	}
 
 	generator := randomGenerator{
-		dimensionsNo:                len(restrictions),
-		restrictions:                restrictions,
-		probabilityToChange:         probabilityToChange,
-		reverse_probabilityToChange: reverse_probabilityToChange,
-		adjustSingleValue:           adjustSingleValue,
-		optimalSlicePercent:         optimalSlicePercent,
-		pointsNo:                    pointsNo,
-		cores:                       cores,
-		algorithm:                   algorithm,
-		minPointsNo:                 minPointsNo,
-		index:                       make([]int, cores),
+		dimensionsNo:        len(restrictions),
+		restrictions:        restrictions,
+		constraints:         constraints,
+		probabilityToChange: probabilityToChange,
+		adjustSingleValue:   adjustSingleValue,
+		optimalSlicePercent: optimalSlicePercent,
+		pointsNo:            pointsNo,
+		cores:               cores,
+		algorithm:           algorithm,
+		minPointsNo:         minPointsNo,
+		index:               make([]int, cores),
 	}
 
 	generator.rs = rs
+	generator.seed = seed
+
+	generator.adaptation = adaptation
+	generator.probabilities = make([][]float64, cores)
+	generator.credit = make([]*credit, cores)
+	for w := range generator.probabilities {
+		generator.probabilities[w] = probabilityToChange
+		if adaptation.adaptive() {
+			// each worker adapts its own copy, the probabilities that are not explicit are 1.0
+			generator.probabilities[w] = make([]float64, len(restrictions))
+			for dimIdx := range restrictions {
+				generator.probabilities[w][dimIdx] = 1.0
+				if dimIdx < len(probabilityToChange) {
+					generator.probabilities[w][dimIdx] = probabilityToChange[dimIdx]
+				}
+			}
+			generator.credit[w] = &credit{
+				trials:    make([]int, len(restrictions)),
+				successes: make([]int, len(restrictions)),
+			}
+		}
+	}
 
 	return generator
 }
 
+// Normalizes the probabilities to change (in place)
+func normalizeProbabilities(probabilityToChange []float64, adjustSingleValue bool) {
+	if adjustSingleValue {
+		// adjust the probabilityToChange values to sum up to 1.0
+		// normalize the values so the sum gives one
+		sum := float64(0.0)
+		for _, value := range probabilityToChange {
+			sum += value
+		}
+		if sum != 1.0 {
+			factor := 1.0 / sum
+			for key, value := range probabilityToChange {
+				probabilityToChange[key] = value * factor
+			}
+		}
+	} else {
+		// make sure at least one value changes each step
+		factor := 0.0
+		for _, p := range probabilityToChange {
+			if p >= factor {
+				factor = p
+			}
+		}
+
+		for key, value := range probabilityToChange {
+			probabilityToChange[key] = value / factor
+		}
+	}
+}
+
+// The probabilities to change that follow the importance of the dimensions on the points of the worker
+// (the dimensions that explain no variance keep a small probability)
+func (g randomGenerator) importanceProbabilities(w int, state GeneratorState) []float64 {
+	r := rand.New(rand.NewSource(g.seed + int64(w)))
+	probabilities := Importance(g.restrictions, state.GeneratedPoints, state.values(), r)
+	for dimIdx, importance := range probabilities {
+		probabilities[dimIdx] = math.Max(importance, MinImportance)
+	}
+	normalizeProbabilities(probabilities, g.adjustSingleValue)
+	return probabilities
+}
+
+// Nelder-Mead search on the continuous dimensions, around the point
+func (g randomGenerator) Refine(start functions.MultidimensionalPoint, value float64, budget int) Generator {
+	return newNelderMead(g.restrictions, g.constraints, start, value, budget)
+}
+
 // Check if improvement was made
+// (for multi-objective functions if the last point is not dominated by the previous ones)
 func (g randomGenerator) Improvement(state GeneratorState) bool {
 	previousOutputLength := len(state.Output)
 
+	if len(state.Objectives) == previousOutputLength && previousOutputLength > 0 {
+		return nonDominated(state)
+	}
+
+	last := state.value(previousOutputLength - 1)
+	if math.IsInf(last, 1) {
+		return false
+	}
+
 	// TODO - Store this in the state
 	min, max := math.MaxFloat64, -math.MaxFloat64
-	for _, value := range state.Output {
+	for _, value := range state.values() {
+		if math.IsInf(value, 1) {
+			continue
+		}
 		if min > value {
 			min = value
 		}
@@ -250,38 +700,93 @@ func (g randomGenerator) Improvement(state GeneratorState) bool {
 
 	boundary := min + (max-min)/100.0*g.optimalSlicePercent
 
-	if state.Output[previousOutputLength-1] < boundary {
+	if last < boundary {
 		return true
 	}
 
 	return false
 }
 
+// Checks if the objectives of the last point are not dominated by the ones of the previous points
+// (pruned points are neither dominated nor dominating)
+func nonDominated(state GeneratorState) bool {
+	last := len(state.Objectives) - 1
+	if math.IsInf(state.value(last), 1) {
+		return false
+	}
+	for idx, values := range state.Objectives[:last] {
+		if !math.IsInf(state.value(idx), 1) && functions.Dominates(values, state.Objectives[last]) {
+			return false
+		}
+	}
+	return true
+}
+
+// Checks if the last output is the best (lowest) one so far
+func newBest(state GeneratorState) bool {
+	last := len(state.Output) - 1
+	if last < 0 || math.IsInf(state.value(last), 1) {
+		return false
+	}
+	for idx := 0; idx < last; idx++ {
+		if state.value(idx) <= state.value(last) {
+			return false
+		}
+	}
+	return true
+}
+
 // Generates a new point
 // Each point is a collection of g.DimensionsNo uniform random values bounded to g.Restrictions
+// Points that don't satisfy the constraints are rejected and generated again
 func (g randomGenerator) Next(w int, initialState GeneratorState) (point functions.MultidimensionalPoint, state GeneratorState) {
 
+	state = initialState
+
+	if g.adaptation == ImportanceProbabilities && g.index[w] > 0 && g.index[w] == g.minPointsNo/g.cores {
+		// the tuning phase is over
+		g.probabilities[w] = g.importanceProbabilities(w, state)
+	}
+
+	if g.adaptation.adaptive() && g.credit[w].changed != nil && len(state.Output) == len(state.GeneratedPoints) {
+		// the outcome of the previous point, a new non-dominated point for multi-objective functions
+		if len(state.Objectives) == len(state.Output) {
+			g.adapt(w, nonDominated(state))
+		} else {
+			g.adapt(w, newBest(state))
+		}
+	}
+
+	point = sampleFeasible(g.constraints, func() functions.MultidimensionalPoint {
+		return g.newPoint(w, state)
+	})
+
+	state.GeneratedPoints = append(state.GeneratedPoints, point)
+	state.Probabilities = append(state.Probabilities, append([]float64(nil), g.probabilities[w]...))
+
+	if g.adaptation.adaptive() && g.index[w] >= g.minPointsNo/g.cores && len(state.Centroid.Values) > 0 {
+		// the search phase, the point tells which dimensions are worth changing
+		g.recordChanges(w, point, state.Centroid)
+	}
+
+	g.index[w]++
+
+	return
+}
+
+// Generates a candidate point, regardless of the constraints
+func (g randomGenerator) newPoint(w int, state GeneratorState) (point functions.MultidimensionalPoint) {
+
 	values := make(map[string]interface{})
 	labels := make([]string, g.dimensionsNo)
 	currentIndex := g.index[w]
 
-	state = initialState
 	if len(state.GeneratedPoints) > 0 {
 		// we have state (either we have generated some numbers or this is the provided initial state)
 
 		//previousPoint := state.GeneratedPoints[len(state.GeneratedPoints)-1]
 
-		// check if previous point was an improvement
-		var wasAnImprovement = false
-		if currentIndex >= g.minPointsNo/g.cores {
-			wasAnImprovement = g.Improvement(state)
-		}
-
-		var probabilities = g.probabilityToChange
-		if wasAnImprovement {
-			// we reverse probabilities if the value was an improvement
-			// probabilities = g.reverse_probabilityToChange
-		}
+		var probabilities = g.probabilities[w]
 
 		// Each value changes with this probability
 		globalProbabilityToChange := g.rs[w].Float64()
@@ -290,10 +795,20 @@ func (g randomGenerator) Next(w int, initialState GeneratorState) (point functio
 		if g.adjustSingleValue {
 
 			// Identify which value should change
+			// (only the dimensions active in the centroid are considered)
+			total := float64(0.0)
+			for key, value := range probabilities {
+				if getRestrictionPerDimension(g, key).isActive(state.Centroid.Values) {
+					total += value
+				}
+			}
 			sum := float64(0.0)
 			for key, value := range probabilities {
+				if !getRestrictionPerDimension(g, key).isActive(state.Centroid.Values) {
+					continue
+				}
 				sum += value
-				if globalProbabilityToChange <= sum {
+				if globalProbabilityToChange*total <= sum {
 					indexToChange = key
 					break
 				}
@@ -302,9 +817,12 @@ func (g randomGenerator) Next(w int, initialState GeneratorState) (point functio
 		} else {
 			change := false
 			for !change {
-				// check if at least one dimension changes
+				// check if at least one (active) dimension changes
 				for dimIdx := 0; dimIdx < g.dimensionsNo; dimIdx++ {
-					if g.probabilityToChange[dimIdx] >= globalProbabilityToChange {
+					if !getRestrictionPerDimension(g, dimIdx).isActive(state.Centroid.Values) {
+						continue
+					}
+					if len(probabilities) <= dimIdx || probabilities[dimIdx] >= globalProbabilityToChange {
 						change = true
 						break
 					}
@@ -340,7 +858,7 @@ func (g randomGenerator) Next(w int, initialState GeneratorState) (point functio
 				} else {
 					// we are in the case where multiple values change...
 					// thy to get the probability to change for each one
-					if len(g.probabilityToChange) > dimIdx {
+					if len(probabilities) > dimIdx {
 						probabilityToChange = probabilities[dimIdx]
 					} else {
 						// if the probability is not explicit, consider it 1.0
@@ -349,11 +867,17 @@ func (g randomGenerator) Next(w int, initialState GeneratorState) (point functio
 				}
 			}
 
-			lowerBound, upperBound, lambda, distribution, samples, label := getRestrictionsPerDimension(g, dimIdx)
-			labels[dimIdx] = label
+			restriction := getRestrictionPerDimension(g, dimIdx)
+			labels[dimIdx] = restriction.Label
+
+			if !restriction.isActive(values) {
+				// the dimension is not relevant for this point
+				continue
+			}
 
-			if probabilityToChange >= globalProbabilityToChange {
-				// change
+			_, inCentroid := state.Centroid.Values[labels[dimIdx]]
+			if probabilityToChange >= globalProbabilityToChange || !inCentroid {
+				// change (a dimension which was inactive in the centroid always gets a new value)
 				if g.algorithm == Leapfrog {
 					if g.index[w] == 0 {
 						// Set the counter in place
@@ -368,22 +892,7 @@ func (g randomGenerator) Next(w int, initialState GeneratorState) (point functio
 					}
 				}
 
-				switch distribution {
-				case Uniform:
-					_, values[labels[dimIdx]] = Float64(lowerBound, upperBound, g.rs[w])
-				case Exponential:
-					_, values[labels[dimIdx]] = ExpFloat64(lambda, g.rs[w])
-				case Discrete:
-					raw := g.rs[w].Float64()
-					sum := 0.0
-					for key, value := range samples {
-						sum += value
-						if raw <= sum {
-							values[labels[dimIdx]] = key
-							break
-						}
-					}
-				}
+				values[labels[dimIdx]] = restriction.generate(g.rs[w])
 
 			} else {
 				// preserve
@@ -401,8 +910,13 @@ func (g randomGenerator) Next(w int, initialState GeneratorState) (point functio
 
 		for dimIdx := 0; dimIdx < g.dimensionsNo; dimIdx++ {
 
-			lowerBound, upperBound, lambda, distribution, samples, label := getRestrictionsPerDimension(g, dimIdx)
-			labels[dimIdx] = label
+			restriction := getRestrictionPerDimension(g, dimIdx)
+			labels[dimIdx] = restriction.Label
+
+			if !restriction.isActive(values) {
+				// the dimension is not relevant for this point
+				continue
+			}
 
 			if g.algorithm == Leapfrog {
 				if g.index[w] == 0 {
@@ -418,22 +932,7 @@ func (g randomGenerator) Next(w int, initialState GeneratorState) (point functio
 				}
 			}
 
-			switch distribution {
-			case Uniform:
-				_, values[labels[dimIdx]] = Float64(lowerBound, upperBound, g.rs[w])
-			case Exponential:
-				_, values[labels[dimIdx]] = ExpFloat64(lambda, g.rs[w])
-			case Discrete:
-				raw := g.rs[w].Float64()
-				sum := 0.0
-				for key, value := range samples {
-					sum += value
-					if raw <= sum {
-						values[labels[dimIdx]] = key
-						break
-					}
-				}
-			}
+			values[labels[dimIdx]] = restriction.generate(g.rs[w])
 
 		}
 
@@ -441,27 +940,17 @@ func (g randomGenerator) Next(w int, initialState GeneratorState) (point functio
 
 	}
 
-	state.GeneratedPoints = append(state.GeneratedPoints, point)
-
-	g.index[w]++
-
 	return
 }
 
-func getRestrictionsPerDimension(g randomGenerator, dimIdx int) (float64, float64, float64, Distribution, map[interface{}]float64, string) {
-	lowerBound, upperBound, lambda := -math.MaxFloat64, math.MaxFloat64, 1.0
-	distribution := Uniform
-	var samples map[interface{}]float64
-	label := ""
+// Retrieves the generation strategy of a dimension (uniform over the whole float64 range if not specified)
+func getRestrictionPerDimension(g randomGenerator, dimIdx int) GenerationStrategy {
 	if len(g.restrictions) > dimIdx {
-		lowerBound = g.restrictions[dimIdx].LowerBound
-		upperBound = g.restrictions[dimIdx].UpperBound
-		lambda = g.restrictions[dimIdx].Lambda
-		distribution = g.restrictions[dimIdx].Distribution
-		samples = g.restrictions[dimIdx].Values
-		label = g.restrictions[dimIdx].Label
-	}
-	return lowerBound, upperBound, lambda, distribution, samples, label
+		return g.restrictions[dimIdx]
+	}
+	return GenerationStrategy{
+		Distribution: Uniform, Lambda: 1.0, LowerBound: -math.MaxFloat64, UpperBound: math.MaxFloat64,
+	}
 }
 
 func (g randomGenerator) HasNext(w int) bool {
@@ -0,0 +1,385 @@
+package generators
+
+import (
+	"github.com/acflorea/goptim/functions"
+	"math"
+	"math/rand"
+	"sync"
+)
+
+// The number of random points proposed by default before the model is used
+var DefaultInitialPoints = 10
+
+// The number of random candidates on which the acquisition function is evaluated
+var AcquisitionCandidates = 1000
+
+// The number of candidates obtained by perturbing the best point found so far
+var LocalCandidates = 200
+
+// The weight of the standard deviation in the upper confidence bound
+var UCBKappa = 2.0
+
+// The trials seen by a model based generator
+// The workers share them under the ManagerWorker algorithm, otherwise each worker learns only from its own trials
+type history struct {
+	shared  bool
+	points  [][]functions.MultidimensionalPoint
+	outputs [][]float64
+	// The number of results of each worker already recorded
+	told  []int
+	mutex *sync.Mutex
+}
+
+func newHistory(cores int, algorithm Algorithm) *history {
+	return &history{
+		shared:  algorithm == ManagerWorker,
+		points:  make([][]functions.MultidimensionalPoint, cores),
+		outputs: make([][]float64, cores),
+		told:    make([]int, cores),
+		mutex:   &sync.Mutex{},
+	}
+}
+
+// Records the results of worker w found in the state (the outputs of the points it generated)
+// and returns the trials the worker learns from
+func (h *history) update(w int, state GeneratorState) ([]functions.MultidimensionalPoint, []float64) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	idx := w
+	if h.shared {
+		idx = 0
+	}
+	for ; h.told[w] < len(state.Output) && h.told[w] < len(state.GeneratedPoints); h.told[w]++ {
+		output := state.value(h.told[w])
+		if math.IsNaN(output) || math.IsInf(output, 0) {
+			continue
+		}
+		h.points[idx] = append(h.points[idx], state.GeneratedPoints[h.told[w]])
+		h.outputs[idx] = append(h.outputs[idx], output)
+	}
+
+	// the other workers only append, the returned trials do not change
+	n := len(h.outputs[idx])
+	return h.points[idx][:n:n], h.outputs[idx][:n:n]
+}
+
+// Bayesian optimization generator
+// A gaussian process is fitted to the trials seen so far and the next point is the candidate
+// that maximizes the acquisition function
+type gpGenerator struct {
+	// The generation strategy on each dimension
+	restrictions []GenerationStrategy
+	// Constraints between dimensions, each generated point satisfies all of them
+	constraints []Constraint
+	// The criterion used to pick the next point
+	acquisition Acquisition
+	// The number of random points generated before the model is used
+	initialPointsNo int
+	// How many points to generate per worker
+	pointsNo int
+	// The trials seen so far
+	history *history
+	// A random generator per worker
+	rngs []*rand.Rand
+	// The number of points generated by each worker
+	index []int
+}
+
+// Generates points by maximizing the acquisition function of a gaussian process fitted to the previous trials
+// The first initialPointsNo points are random
+// The discrete dimensions are compared by equality, the others by their position in the
+// cumulative distribution function (so the model sees a logarithmic dimension on a logarithmic scale)
+func NewGaussianProcess(restrictions []GenerationStrategy, constraints []Constraint, acquisition Acquisition,
+	initialPointsNo, pointsNo, cores int, algorithm Algorithm, seed int64) Generator {
+
+	rngs := make([]*rand.Rand, cores)
+	for w := range rngs {
+		rngs[w] = rand.New(rand.NewSource(seed + int64(w)*1000003))
+	}
+
+	return &gpGenerator{
+		restrictions:    restrictions,
+		constraints:     constraints,
+		acquisition:     acquisition,
+		initialPointsNo: initialPointsNo,
+		pointsNo:        pointsNo,
+		history:         newHistory(cores, algorithm),
+		rngs:            rngs,
+		index:           make([]int, cores),
+	}
+}
+
+func (g *gpGenerator) Next(w int, initialState GeneratorState) (point functions.MultidimensionalPoint, state GeneratorState) {
+
+	state = initialState
+
+	points, outputs := g.history.update(w, state)
+	if len(points) < g.initialPointsNo || len(points) == 0 {
+		point = randomPoint(g.restrictions, g.constraints, g.rngs[w])
+	} else {
+		point = g.propose(points, outputs, g.rngs[w])
+	}
+
+	state.GeneratedPoints = append(state.GeneratedPoints, point)
+
+	g.index[w]++
+
+	return
+}
+
+func (g *gpGenerator) HasNext(w int) bool {
+	return g.index[w] < g.pointsNo
+}
+
+func (g *gpGenerator) Improvement(state GeneratorState) bool {
+	return newBest(state)
+}
+
+// The feasible candidate with the best acquisition value
+func (g *gpGenerator) propose(points []functions.MultidimensionalPoint, outputs []float64,
+	r *rand.Rand) functions.MultidimensionalPoint {
+
+	x := make([][]float64, len(points))
+	bestIdx := 0
+	for idx, point := range points {
+		x[idx] = encode(g.restrictions, point)
+		if outputs[idx] < outputs[bestIdx] {
+			bestIdx = idx
+		}
+	}
+
+	model := fitGaussianProcess(g.restrictions, x, outputs)
+	best := (outputs[bestIdx] - model.mean) / model.scale
+
+	var proposal functions.MultidimensionalPoint
+	proposalScore := math.Inf(-1)
+	for c := 0; c < AcquisitionCandidates+LocalCandidates; c++ {
+		var candidate functions.MultidimensionalPoint
+		if c < AcquisitionCandidates {
+			candidate = unitToPoint(g.restrictions, randomCoordinates(len(g.restrictions), r))
+		} else {
+			candidate = unitToPoint(g.restrictions, perturb(x[bestIdx], 0.1, r))
+		}
+		if !Feasible(candidate, g.constraints) {
+			continue
+		}
+		mu, sigma := model.predict(encode(g.restrictions, candidate))
+		if score := g.acquisition.score(mu, sigma, best); score > proposalScore {
+			proposal, proposalScore = candidate, score
+		}
+	}
+
+	if proposal.Values == nil {
+		// no feasible candidate, fall back to rejection sampling
+		return randomPoint(g.restrictions, g.constraints, r)
+	}
+	return proposal
+}
+
+// The value of the acquisition function for a prediction with mean mu and standard deviation sigma,
+// best is the lowest value found so far (higher scores are better)
+func (a Acquisition) score(mu, sigma, best float64) float64 {
+	// a small margin favours exploration
+	improvement := best - mu - 0.01
+	z := improvement / sigma
+	switch a {
+	case UpperConfidenceBound:
+		// the lower bound, since the target is minimized
+		return -(mu - UCBKappa*sigma)
+	case ProbabilityOfImprovement:
+		return normalCDF(z)
+	}
+	return improvement*normalCDF(z) + sigma*math.Exp(-z*z/2)/math.Sqrt(2*math.Pi)
+}
+
+// A random point of the search space that satisfies the constraints
+func randomPoint(restrictions []GenerationStrategy, constraints []Constraint, r *rand.Rand) functions.MultidimensionalPoint {
+	return sampleFeasible(constraints, func() functions.MultidimensionalPoint {
+		return unitToPoint(restrictions, randomCoordinates(len(restrictions), r))
+	})
+}
+
+func randomCoordinates(dimensionsNo int, r *rand.Rand) []float64 {
+	coordinates := make([]float64, dimensionsNo)
+	for dimIdx := range coordinates {
+		coordinates[dimIdx] = r.Float64()
+	}
+	return coordinates
+}
+
+// Moves the coordinates by normally distributed steps (missing coordinates are drawn at random)
+func perturb(coordinates []float64, stddev float64, r *rand.Rand) []float64 {
+	perturbed := make([]float64, len(coordinates))
+	for dimIdx, u := range coordinates {
+		if math.IsNaN(u) {
+			perturbed[dimIdx] = r.Float64()
+			continue
+		}
+		perturbed[dimIdx] = math.Min(math.Max(u+stddev*r.NormFloat64(), 0), math.Nextafter(1, 0))
+	}
+	return perturbed
+}
+
+// The coordinates of a point in the unit hypercube (NaN for the inactive dimensions)
+func encode(restrictions []GenerationStrategy, point functions.MultidimensionalPoint) []float64 {
+	coordinates := make([]float64, len(restrictions))
+	for dimIdx, restriction := range restrictions {
+		if value, ok := point.Values[restriction.Label]; ok {
+			coordinates[dimIdx] = restriction.unitOf(value)
+		} else {
+			coordinates[dimIdx] = math.NaN()
+		}
+	}
+	return coordinates
+}
+
+// A gaussian process with a Matern 5/2 kernel, fitted to normalized outputs
+type gaussianProcess struct {
+	restrictions []GenerationStrategy
+	x            [][]float64
+	lengthscale  float64
+	// The Cholesky decomposition of the covariance matrix of the trials
+	chol [][]float64
+	// The covariance matrix inverse times the outputs
+	alpha []float64
+	// The outputs are normalized with their mean and standard deviation
+	mean, scale float64
+}
+
+// Fits a gaussian process to the trials, the lengthscale is the one that maximizes the marginal likelihood
+func fitGaussianProcess(restrictions []GenerationStrategy, x [][]float64, y []float64) gaussianProcess {
+
+	mean, scale := 0.0, 0.0
+	for _, value := range y {
+		mean += value / float64(len(y))
+	}
+	for _, value := range y {
+		scale += (value - mean) * (value - mean) / float64(len(y))
+	}
+	scale = math.Sqrt(scale)
+	if scale == 0 {
+		scale = 1
+	}
+	normalized := make([]float64, len(y))
+	for idx, value := range y {
+		normalized[idx] = (value - mean) / scale
+	}
+
+	model := gaussianProcess{restrictions: restrictions, x: x, mean: mean, scale: scale}
+	bestLikelihood := math.Inf(-1)
+	for _, lengthscale := range []float64{0.05, 0.1, 0.2, 0.4, 0.8, 1.6} {
+		candidate := gaussianProcess{restrictions: restrictions, x: x, lengthscale: lengthscale, mean: mean, scale: scale}
+		// the noise also keeps the matrix positive definite when points repeat
+		for noise := 1e-4; candidate.chol == nil; noise *= 10 {
+			candidate.chol = cholesky(candidate.covariance(noise))
+		}
+		candidate.alpha = choleskySolve(candidate.chol, normalized)
+
+		likelihood := 0.0
+		for idx := range normalized {
+			likelihood -= normalized[idx]*candidate.alpha[idx]/2 + math.Log(candidate.chol[idx][idx])
+		}
+		if likelihood > bestLikelihood {
+			model, bestLikelihood = candidate, likelihood
+		}
+	}
+
+	return model
+}
+
+func (gp gaussianProcess) covariance(noise float64) [][]float64 {
+	k := make([][]float64, len(gp.x))
+	for i := range gp.x {
+		k[i] = make([]float64, len(gp.x))
+		for j := 0; j <= i; j++ {
+			k[i][j] = gp.kernel(gp.x[i], gp.x[j])
+			k[j][i] = k[i][j]
+		}
+		k[i][i] += noise
+	}
+	return k
+}
+
+// Matern 5/2 kernel
+// The distance between discrete values (and between active and inactive dimensions) is 0 if they are equal, 1 otherwise
+func (gp gaussianProcess) kernel(a, b []float64) float64 {
+	d2 := 0.0
+	for dimIdx, restriction := range gp.restrictions {
+		x, y := a[dimIdx], b[dimIdx]
+		switch {
+		case math.IsNaN(x) && math.IsNaN(y):
+		case math.IsNaN(x) || math.IsNaN(y) || restriction.Distribution == Discrete:
+			if x != y {
+				d2++
+			}
+		default:
+			d2 += (x - y) * (x - y)
+		}
+	}
+	d := math.Sqrt(5*d2) / gp.lengthscale
+	return (1 + d + d*d/3) * math.Exp(-d)
+}
+
+// The mean and the standard deviation of the (normalized) value at x
+func (gp gaussianProcess) predict(x []float64) (mu, sigma float64) {
+	k := make([]float64, len(gp.x))
+	for idx := range gp.x {
+		k[idx] = gp.kernel(x, gp.x[idx])
+		mu += k[idx] * gp.alpha[idx]
+	}
+	v := forwardSubstitution(gp.chol, k)
+	variance := 1.0
+	for _, value := range v {
+		variance -= value * value
+	}
+	return mu, math.Sqrt(math.Max(variance, 1e-12))
+}
+
+// The lower triangular L with L * L^T = a, nil if a is not positive definite
+func cholesky(a [][]float64) [][]float64 {
+	l := make([][]float64, len(a))
+	for i := range a {
+		l[i] = make([]float64, len(a))
+		for j := 0; j <= i; j++ {
+			sum := a[i][j]
+			for k := 0; k < j; k++ {
+				sum -= l[i][k] * l[j][k]
+			}
+			if i == j {
+				if sum <= 0 {
+					return nil
+				}
+				l[i][i] = math.Sqrt(sum)
+			} else {
+				l[i][j] = sum / l[j][j]
+			}
+		}
+	}
+	return l
+}
+
+// Solves L * x = b
+func forwardSubstitution(l [][]float64, b []float64) []float64 {
+	x := make([]float64, len(b))
+	for i := range b {
+		sum := b[i]
+		for k := 0; k < i; k++ {
+			sum -= l[i][k] * x[k]
+		}
+		x[i] = sum / l[i][i]
+	}
+	return x
+}
+
+// Solves L * L^T * x = b
+func choleskySolve(l [][]float64, b []float64) []float64 {
+	x := forwardSubstitution(l, b)
+	for i := len(x) - 1; i >= 0; i-- {
+		for k := i + 1; k < len(x); k++ {
+			x[i] -= l[k][i] * x[k]
+		}
+		x[i] /= l[i][i]
+	}
+	return x
+}
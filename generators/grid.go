@@ -0,0 +1,202 @@
+package generators
+
+import (
+	"fmt"
+	"github.com/acflorea/goptim/functions"
+	"math"
+	"sync"
+)
+
+// The number of values used by default on the continuous dimensions of a grid
+var DefaultGridResolution = 10
+
+// The maximum number of points of a grid, larger grids need a lower resolution or fewer values
+var MaxGridPoints = 1 << 20
+
+// A generator that enumerates the points of a grid
+// The grid is the cartesian product of the values of all the dimensions: all the values of discrete, integer
+// and quantized dimensions and resolution quantiles of the continuous ones
+type gridGenerator struct {
+	// The points of the grid, in lexicographic order of the dimensions
+	points []functions.MultidimensionalPoint
+	// The level of parallelism
+	cores int
+	// The parallel generation algorithm, decides which points go to which worker
+	algorithm Algorithm
+	// The number of points generated by each worker
+	index []int
+	// The next point of the grid and the point taken by each worker in HasNext (ManagerWorker algorithm, -1 for none)
+	next     *int
+	reserved []int
+	mutex    *sync.Mutex
+}
+
+// Generates the points of a grid
+// resolution is the number of values of the continuous dimensions (the quantiles at 1/2r, 3/2r ... (2r-1)/2r)
+// Inactive conditional dimensions are left out of the points (so no point is generated twice)
+// and the points that violate the constraints are dropped.
+// The grid is split among the workers according to the algorithm (Parametrization splits it like SeqSplit)
+func NewGrid(restrictions []GenerationStrategy, constraints []Constraint, resolution, cores int,
+	algorithm Algorithm) Generator {
+
+	if resolution < 1 {
+		panic(fmt.Errorf("invalid grid resolution %d", resolution))
+	}
+
+	values := make([][]interface{}, len(restrictions))
+	size := 1.0
+	for dimIdx, restriction := range restrictions {
+		values[dimIdx] = restriction.gridValues(resolution)
+		size *= float64(len(values[dimIdx]))
+	}
+	if size > float64(MaxGridPoints) {
+		panic(fmt.Errorf("the grid has up to %.0f points, more than the maximum of %d", size, MaxGridPoints))
+	}
+
+	points := []functions.MultidimensionalPoint{}
+	var enumerate func(dimIdx int, point map[string]interface{})
+	enumerate = func(dimIdx int, point map[string]interface{}) {
+		if dimIdx == len(restrictions) {
+			candidate := functions.MultidimensionalPoint{Values: make(map[string]interface{}, len(point))}
+			for label, value := range point {
+				candidate.Values[label] = value
+			}
+			if Feasible(candidate, constraints) {
+				points = append(points, candidate)
+			}
+			return
+		}
+		restriction := restrictions[dimIdx]
+		if !restriction.isActive(point) {
+			enumerate(dimIdx+1, point)
+			return
+		}
+		for _, value := range values[dimIdx] {
+			point[restriction.Label] = value
+			enumerate(dimIdx+1, point)
+		}
+		delete(point, restriction.Label)
+	}
+	enumerate(0, make(map[string]interface{}))
+
+	if len(points) == 0 {
+		panic(fmt.Errorf("%w: no point of the grid satisfies the constraints", ErrInfeasible))
+	}
+
+	return gridGenerator{
+		points:    points,
+		cores:     cores,
+		algorithm: algorithm,
+		index:     make([]int, cores),
+		next:      new(int),
+		reserved:  reserved(cores),
+		mutex:     &sync.Mutex{},
+	}
+}
+
+func reserved(cores int) []int {
+	indices := make([]int, cores)
+	for w := range indices {
+		indices[w] = -1
+	}
+	return indices
+}
+
+// The values of the dimension that are part of the grid
+func (s GenerationStrategy) gridValues(resolution int) (values []interface{}) {
+	switch s.Distribution {
+	case Discrete:
+		for _, choice := range s.ordered().Choices {
+			values = append(values, choice.Value)
+		}
+	case IntUniform:
+		for value := int(s.LowerBound); value <= int(s.UpperBound); value++ {
+			values = append(values, value)
+		}
+	case QUniform:
+		n := int(math.Floor((s.UpperBound-s.LowerBound)/s.Step+1e-9)) + 1
+		for k := 0; k < n; k++ {
+			values = append(values, gridValue(s.LowerBound, s.Step, k))
+		}
+	default:
+		for k := 0; k < resolution; k++ {
+			value := s.valueAt((float64(k) + 0.5) / float64(resolution))
+			// integer distributions can map neighbouring quantiles to the same value
+			if len(values) == 0 || values[len(values)-1] != value {
+				values = append(values, value)
+			}
+		}
+	}
+	return
+}
+
+// The range of grid points that belong to worker w
+// (the index of the first one, the distance between consecutive ones and their number)
+func (g gridGenerator) share(w int) (first, stride, count int) {
+	n := len(g.points)
+	switch g.algorithm {
+	case Leapfrog:
+		return w, g.cores, (n - w + g.cores - 1) / g.cores
+	case SeqSplit, Parametrization:
+		first = w * n / g.cores
+		return first, 1, (w+1)*n/g.cores - first
+	}
+	// ManagerWorker, the points are handed out in order from the shared cursor
+	return 0, 1, n
+}
+
+func (g gridGenerator) Next(w int, initialState GeneratorState) (point functions.MultidimensionalPoint, state GeneratorState) {
+
+	state = initialState
+
+	var pointIdx int
+	if g.algorithm == ManagerWorker {
+		g.mutex.Lock()
+		pointIdx = g.take(w)
+		g.reserved[w] = -1
+		g.mutex.Unlock()
+	} else {
+		first, stride, _ := g.share(w)
+		pointIdx = first + g.index[w]*stride
+	}
+	// the grid is exhausted, the point is empty
+	if pointIdx < 0 || pointIdx >= len(g.points) {
+		return
+	}
+
+	point = g.points[pointIdx]
+	state.GeneratedPoints = append(state.GeneratedPoints, point)
+
+	g.index[w]++
+
+	return
+}
+
+func (g gridGenerator) HasNext(w int) bool {
+	if g.algorithm == ManagerWorker {
+		// the point is taken right away, so no other worker gets it between HasNext and Next
+		g.mutex.Lock()
+		defer g.mutex.Unlock()
+		g.reserved[w] = g.take(w)
+		return g.reserved[w] >= 0
+	}
+	_, _, count := g.share(w)
+	return g.index[w] < count
+}
+
+// The point taken by worker w, the next one of the grid if it has none (-1 once the grid is exhausted)
+// The caller holds the mutex
+func (g gridGenerator) take(w int) int {
+	if g.reserved[w] >= 0 {
+		return g.reserved[w]
+	}
+	if *g.next < len(g.points) {
+		*g.next++
+		return *g.next - 1
+	}
+	return -1
+}
+
+func (g gridGenerator) Improvement(state GeneratorState) bool {
+	return newBest(state)
+}
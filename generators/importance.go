@@ -0,0 +1,212 @@
+package generators
+
+import (
+	"github.com/acflorea/goptim/functions"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// The number of trees of the random forest the importance of the dimensions is computed on
+var ImportanceTrees = 32
+
+// The minimum number of points in a leaf of the trees
+var ImportanceMinLeaf = 2
+
+// The importance given to the dimensions that explain no variance (so their values can still change)
+var MinImportance = 0.01
+
+// A regression tree on the unit hypercube
+type regressionTree struct {
+	// The split dimension and threshold, the left subtree has the points below the threshold (-1 for leaves)
+	dimension   int
+	threshold   float64
+	left, right *regressionTree
+	// The mean output of the points of a leaf
+	value float64
+}
+
+// The importance of each dimension: the fraction of the variance of the function explained by the dimension alone
+// (its main effect in a functional ANOVA decomposition, F. Hutter et al.), averaged over the trees of a random forest
+// fitted to the points and their outputs
+// Inactive conditional dimensions are encoded as the middle of their range and points with a NaN output are ignored
+func Importance(restrictions []GenerationStrategy, points []functions.MultidimensionalPoint, outputs []float64,
+	r *rand.Rand) []float64 {
+
+	var x [][]float64
+	var y []float64
+	for idx := 0; idx < len(points) && idx < len(outputs); idx++ {
+		if math.IsNaN(outputs[idx]) || math.IsInf(outputs[idx], 0) {
+			continue
+		}
+		coordinates := encode(restrictions, points[idx])
+		for dimIdx, u := range coordinates {
+			if math.IsNaN(u) {
+				coordinates[dimIdx] = 0.5
+			}
+		}
+		x = append(x, coordinates)
+		y = append(y, outputs[idx])
+	}
+
+	importance := make([]float64, len(restrictions))
+	if len(y) < 2*ImportanceMinLeaf {
+		return importance
+	}
+
+	trees := 0
+	for t := 0; t < ImportanceTrees; t++ {
+		// bootstrap sample
+		rows := make([]int, len(y))
+		for idx := range rows {
+			rows[idx] = r.Intn(len(y))
+		}
+		fractions, ok := fitTree(x, y, rows).mainEffects(len(restrictions))
+		if !ok {
+			continue
+		}
+		for dimIdx := range importance {
+			importance[dimIdx] += fractions[dimIdx]
+		}
+		trees++
+	}
+
+	if trees > 0 {
+		for dimIdx := range importance {
+			importance[dimIdx] /= float64(trees)
+		}
+	}
+	return importance
+}
+
+// Fits a regression tree to the rows, each split minimizes the squared error of the two halves
+func fitTree(x [][]float64, y []float64, rows []int) *regressionTree {
+
+	mean := 0.0
+	for _, row := range rows {
+		mean += y[row] / float64(len(rows))
+	}
+	tree := &regressionTree{dimension: -1, value: mean}
+	if len(rows) < 2*ImportanceMinLeaf {
+		return tree
+	}
+
+	bestError := math.Inf(1)
+	for dimIdx := range x[rows[0]] {
+		sorted := append([]int(nil), rows...)
+		sort.SliceStable(sorted, func(a, b int) bool { return x[sorted[a]][dimIdx] < x[sorted[b]][dimIdx] })
+
+		total, totalSquares := 0.0, 0.0
+		for _, row := range sorted {
+			total += y[row]
+			totalSquares += y[row] * y[row]
+		}
+		sum, squares := 0.0, 0.0
+		for k := 1; k < len(sorted); k++ {
+			sum += y[sorted[k-1]]
+			squares += y[sorted[k-1]] * y[sorted[k-1]]
+			lower, upper := x[sorted[k-1]][dimIdx], x[sorted[k]][dimIdx]
+			if lower == upper || k < ImportanceMinLeaf || len(sorted)-k < ImportanceMinLeaf {
+				continue
+			}
+			// the sum of the squared errors of both halves
+			n := float64(k)
+			splitError := squares - sum*sum/n + (totalSquares - squares) - (total-sum)*(total-sum)/(float64(len(sorted))-n)
+			if splitError < bestError-1e-12 {
+				bestError = splitError
+				tree.dimension, tree.threshold = dimIdx, (lower+upper)/2
+			}
+		}
+	}
+
+	if tree.dimension < 0 {
+		return tree
+	}
+
+	var left, right []int
+	for _, row := range rows {
+		if x[row][tree.dimension] < tree.threshold {
+			left = append(left, row)
+		} else {
+			right = append(right, row)
+		}
+	}
+	tree.left, tree.right = fitTree(x, y, left), fitTree(x, y, right)
+	return tree
+}
+
+// A leaf of a tree and the box of the unit hypercube it covers
+type leaf struct {
+	value        float64
+	lower, upper []float64
+}
+
+func (t *regressionTree) leaves(lower, upper []float64, found []leaf) []leaf {
+	if t.dimension < 0 {
+		return append(found, leaf{value: t.value, lower: lower, upper: upper})
+	}
+	leftUpper := append([]float64(nil), upper...)
+	leftUpper[t.dimension] = math.Min(upper[t.dimension], t.threshold)
+	rightLower := append([]float64(nil), lower...)
+	rightLower[t.dimension] = math.Max(lower[t.dimension], t.threshold)
+	found = t.left.leaves(lower, leftUpper, found)
+	return t.right.leaves(rightLower, upper, found)
+}
+
+// The fraction of the variance of the tree (over the uniform unit hypercube) explained by each dimension alone
+// Returns false if the tree is constant
+func (t *regressionTree) mainEffects(dimensionsNo int) ([]float64, bool) {
+
+	lower, upper := make([]float64, dimensionsNo), make([]float64, dimensionsNo)
+	for dimIdx := range upper {
+		upper[dimIdx] = 1
+	}
+	leaves := t.leaves(lower, upper, nil)
+
+	volume := func(l leaf, skip int) float64 {
+		v := 1.0
+		for dimIdx := range l.lower {
+			if dimIdx != skip {
+				v *= l.upper[dimIdx] - l.lower[dimIdx]
+			}
+		}
+		return v
+	}
+
+	mean, variance := 0.0, 0.0
+	for _, l := range leaves {
+		mean += volume(l, -1) * l.value
+	}
+	for _, l := range leaves {
+		variance += volume(l, -1) * (l.value - mean) * (l.value - mean)
+	}
+	if variance <= 0 {
+		return nil, false
+	}
+
+	fractions := make([]float64, dimensionsNo)
+	for dimIdx := range fractions {
+		// the marginal of the dimension is constant between the bounds of the leaves
+		bounds := []float64{0, 1}
+		for _, l := range leaves {
+			bounds = append(bounds, l.lower[dimIdx], l.upper[dimIdx])
+		}
+		sort.Float64s(bounds)
+		for k := 1; k < len(bounds); k++ {
+			width := bounds[k] - bounds[k-1]
+			if width <= 0 {
+				continue
+			}
+			middle := (bounds[k] + bounds[k-1]) / 2
+			marginal := 0.0
+			for _, l := range leaves {
+				if l.lower[dimIdx] <= middle && middle < l.upper[dimIdx] {
+					marginal += volume(l, dimIdx) * l.value
+				}
+			}
+			fractions[dimIdx] += width * (marginal - mean) * (marginal - mean)
+		}
+		fractions[dimIdx] /= variance
+	}
+	return fractions, true
+}
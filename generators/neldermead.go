@@ -0,0 +1,272 @@
+package generators
+
+import (
+	"github.com/acflorea/goptim/functions"
+	"math"
+	"sort"
+)
+
+// The size of the initial Nelder-Mead simplex (relative to the range of the dimensions)
+var NelderMeadStep = 0.1
+
+// Generators that can refine a point with a local search
+type Refinable interface {
+	// A generator of at most budget points that searches around start (whose value is known)
+	// nil if there is nothing to refine
+	// The search may end before HasNext can tell (it learns the last values in Next), Next then returns an empty point
+	Refine(start functions.MultidimensionalPoint, value float64, budget int) Generator
+}
+
+// The stages of a Nelder-Mead iteration
+const (
+	nmInitial = iota
+	nmReflection
+	nmExpansion
+	nmOutsideContraction
+	nmInsideContraction
+	nmShrink
+)
+
+// Nelder-Mead search on the continuous dimensions of a point, the other dimensions keep their values
+// The search runs on the positions of the values in the cumulative distribution functions
+// and the vertices are clamped to the unit hypercube.
+// The points proposed by an iteration are queued and the iteration goes on once all of them are evaluated,
+// infeasible points get an infinite value without being evaluated.
+type nelderMead struct {
+	restrictions []GenerationStrategy
+	constraints  []Constraint
+	// The point the search starts from
+	start functions.MultidimensionalPoint
+	// The indexes of the dimensions searched
+	dims []int
+	// The vertices of the simplex and their values
+	simplex [][]float64
+	values  []float64
+	stage   int
+	// The reflected vertex and its value
+	reflected      []float64
+	reflectedValue float64
+	// The points of the current stage, the ones handed out and the values received so far
+	queue   [][]float64
+	issued  int
+	results []float64
+	// The outputs of the state already used
+	told   int
+	budget int
+	index  int
+	done   bool
+}
+
+func newNelderMead(restrictions []GenerationStrategy, constraints []Constraint,
+	start functions.MultidimensionalPoint, value float64, budget int) Generator {
+
+	nm := &nelderMead{restrictions: restrictions, constraints: constraints, start: start, budget: budget}
+	for dimIdx, restriction := range restrictions {
+		if _, ok := start.Values[restriction.Label]; ok && restriction.continuous() {
+			nm.dims = append(nm.dims, dimIdx)
+		}
+	}
+	if len(nm.dims) == 0 || budget <= 0 {
+		return nil
+	}
+
+	x0 := make([]float64, len(nm.dims))
+	for i, dimIdx := range nm.dims {
+		x0[i] = restrictions[dimIdx].unitOf(start.Values[restrictions[dimIdx].Label])
+	}
+	nm.simplex, nm.values = [][]float64{x0}, []float64{value}
+	nm.stage = nmInitial
+	for i := range x0 {
+		vertex := append([]float64(nil), x0...)
+		// step inwards from the upper bound
+		if vertex[i]+NelderMeadStep < 1 {
+			vertex[i] += NelderMeadStep
+		} else {
+			vertex[i] -= NelderMeadStep
+		}
+		nm.queue = append(nm.queue, vertex)
+	}
+	nm.skipInfeasible()
+
+	return nm
+}
+
+// Checks if the values of the distribution are continuous
+func (s GenerationStrategy) continuous() bool {
+	switch s.Distribution {
+	case Uniform, Exponential, LogUniform, Normal, TruncatedNormal, LogNormal:
+		return true
+	}
+	return false
+}
+
+func (nm *nelderMead) Next(w int, initialState GeneratorState) (point functions.MultidimensionalPoint, state GeneratorState) {
+
+	state = initialState
+
+	// the refinement state only holds the points of the refinement
+	for ; nm.told < len(state.Output) && nm.told < len(state.GeneratedPoints); nm.told++ {
+		nm.results = append(nm.results, state.value(nm.told))
+		if len(nm.results) == len(nm.queue) {
+			nm.advance()
+			nm.skipInfeasible()
+		}
+	}
+
+	if nm.done || nm.issued >= len(nm.queue) {
+		// the search is over, the results of the last points showed it
+		nm.done = true
+		return
+	}
+	point = nm.point(nm.queue[nm.issued])
+	nm.issued++
+
+	state.GeneratedPoints = append(state.GeneratedPoints, point)
+
+	nm.index++
+
+	return
+}
+
+func (nm *nelderMead) HasNext(w int) bool {
+	return !nm.done && nm.index < nm.budget
+}
+
+func (nm *nelderMead) Improvement(state GeneratorState) bool {
+	return newBest(state)
+}
+
+// The point with the searched dimensions at the given coordinates
+func (nm *nelderMead) point(x []float64) functions.MultidimensionalPoint {
+	values := make(map[string]interface{}, len(nm.start.Values))
+	for label, value := range nm.start.Values {
+		values[label] = value
+	}
+	for i, dimIdx := range nm.dims {
+		restriction := nm.restrictions[dimIdx]
+		values[restriction.Label] = restriction.valueAt(math.Max(0, math.Min(x[i], math.Nextafter(1, 0))))
+	}
+	return functions.MultidimensionalPoint{Values: values}
+}
+
+// Gives an infinite value to the infeasible points at the head of the queue
+func (nm *nelderMead) skipInfeasible() {
+	for attempt := 0; !nm.done && nm.issued < len(nm.queue); attempt++ {
+		if Feasible(nm.point(nm.queue[nm.issued]), nm.constraints) {
+			return
+		}
+		if attempt >= MaxConstraintAttempts {
+			nm.done = true
+			return
+		}
+		nm.issued++
+		nm.results = append(nm.results, math.Inf(1))
+		if len(nm.results) == len(nm.queue) {
+			nm.advance()
+		}
+	}
+}
+
+// Moves to the next stage once all the points of the current one are evaluated
+func (nm *nelderMead) advance() {
+
+	n := len(nm.dims)
+
+	switch nm.stage {
+	case nmInitial, nmShrink:
+		if nm.stage == nmInitial {
+			nm.simplex = append(nm.simplex, nm.queue...)
+			nm.values = append(nm.values, nm.results...)
+		} else {
+			copy(nm.simplex[1:], nm.queue)
+			copy(nm.values[1:], nm.results)
+		}
+	case nmReflection:
+		nm.reflectedValue = nm.results[0]
+		switch {
+		case nm.reflectedValue < nm.values[0]:
+			nm.propose(nmExpansion, 2)
+			return
+		case nm.reflectedValue < nm.values[n-1]:
+			nm.simplex[n], nm.values[n] = nm.reflected, nm.reflectedValue
+		case nm.reflectedValue < nm.values[n]:
+			nm.propose(nmOutsideContraction, 0.5)
+			return
+		default:
+			nm.propose(nmInsideContraction, 0.5)
+			return
+		}
+	case nmExpansion:
+		if nm.results[0] < nm.reflectedValue {
+			nm.simplex[n], nm.values[n] = nm.queue[0], nm.results[0]
+		} else {
+			nm.simplex[n], nm.values[n] = nm.reflected, nm.reflectedValue
+		}
+	case nmOutsideContraction, nmInsideContraction:
+		if (nm.stage == nmOutsideContraction && nm.results[0] <= nm.reflectedValue) ||
+			(nm.stage == nmInsideContraction && nm.results[0] < nm.values[n]) {
+			nm.simplex[n], nm.values[n] = nm.queue[0], nm.results[0]
+		} else {
+			// shrink towards the best vertex
+			queue := make([][]float64, n)
+			for i := range queue {
+				queue[i] = make([]float64, n)
+				for j := range queue[i] {
+					queue[i][j] = nm.simplex[0][j] + 0.5*(nm.simplex[i+1][j]-nm.simplex[0][j])
+				}
+			}
+			nm.stage, nm.queue, nm.issued, nm.results = nmShrink, queue, 0, nil
+			return
+		}
+	}
+
+	// a new iteration
+	order := make([]int, n+1)
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return nm.values[order[i]] < nm.values[order[j]] })
+	simplex, values := make([][]float64, n+1), make([]float64, n+1)
+	for rank, i := range order {
+		simplex[rank], values[rank] = nm.simplex[i], nm.values[i]
+	}
+	nm.simplex, nm.values = simplex, values
+
+	// the simplex collapsed
+	diameter := 0.0
+	for _, vertex := range nm.simplex[1:] {
+		for j := range vertex {
+			diameter = math.Max(diameter, math.Abs(vertex[j]-nm.simplex[0][j]))
+		}
+	}
+	if diameter < 1e-9 {
+		nm.done = true
+		return
+	}
+
+	nm.propose(nmReflection, -1)
+	nm.reflected = nm.queue[0]
+}
+
+// Queues the point centroid + coefficient * (vertex - centroid), where the centroid is the one of the best n vertices
+// and the vertex is the worst one for the reflection and the inside contraction, the reflected one otherwise
+func (nm *nelderMead) propose(stage int, coefficient float64) {
+	n := len(nm.dims)
+	centroid := make([]float64, n)
+	for _, vertex := range nm.simplex[:n] {
+		for j := range vertex {
+			centroid[j] += vertex[j] / float64(n)
+		}
+	}
+	x := make([]float64, n)
+	for j := range x {
+		if stage == nmReflection || stage == nmInsideContraction {
+			x[j] = centroid[j] + coefficient*(nm.simplex[n][j]-centroid[j])
+		} else {
+			// relative to the reflected vertex
+			x[j] = centroid[j] + coefficient*(nm.reflected[j]-centroid[j])
+		}
+		x[j] = math.Max(0, math.Min(x[j], math.Nextafter(1, 0)))
+	}
+	nm.stage, nm.queue, nm.issued, nm.results = stage, [][]float64{x}, 0, nil
+}
@@ -0,0 +1,130 @@
+package generators
+
+import (
+	"github.com/acflorea/goptim/functions"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// NSGA-II (K. Deb et al.), for multi-objective functions
+// The population is ranked by fronts of non-dominated individuals and, within a front, by crowding distance
+// (individuals in sparse regions of the front first). The parents are selected by tournament on this rank and the
+// offspring are bred as in the genetic algorithm; the best ranked of parents and offspring survive.
+type nsga2 struct {
+	// Breeds the offspring, its population is kept in rank order
+	*geneticAlgorithm
+	// The objectives of the individuals of the population
+	objectives [][]float64
+}
+
+// Generates points with NSGA-II, the population has 10 individuals per dimension (at least 10)
+// The points are ranked on GeneratorState.Objectives (on Output for single objective functions)
+func NewNSGA2(restrictions []GenerationStrategy, constraints []Constraint, pointsNo, cores int, seed int64) Generator {
+
+	evolution := &nsga2{
+		geneticAlgorithm: &geneticAlgorithm{
+			restrictions: restrictions,
+			size:         int(math.Max(10, float64(10*len(restrictions)))),
+		},
+	}
+	return newPopulationGenerator(restrictions, constraints, evolution, pointsNo, cores, seed)
+}
+
+func (e *nsga2) tell(trials []trial, r *rand.Rand) {
+
+	coordinates, objectives := e.population.coordinates, e.objectives
+	for _, t := range trials {
+		coordinates = append(coordinates, t.coordinates)
+		if t.values != nil {
+			objectives = append(objectives, t.values)
+		} else {
+			objectives = append(objectives, []float64{t.value})
+		}
+	}
+
+	order, ranks := rank(objectives)
+	if len(order) > e.size {
+		order = order[:e.size]
+	}
+
+	e.population = individuals{coordinates: make([][]float64, len(order)), values: make([]float64, len(order))}
+	e.objectives = make([][]float64, len(order))
+	for position, idx := range order {
+		e.population.coordinates[position] = coordinates[idx]
+		// the tournament prefers the lower positions, the values are the fronts
+		e.population.values[position] = float64(ranks[idx])
+		e.objectives[position] = objectives[idx]
+	}
+}
+
+// Orders the points by front (the non-dominated ones, then the ones dominated only by them and so on)
+// and by decreasing crowding distance within a front
+// Returns the order and the front of each point
+func rank(objectives [][]float64) (order []int, fronts []int) {
+
+	n := len(objectives)
+	fronts = make([]int, n)
+	// the points each point dominates and the number of points that dominate it
+	dominated := make([][]int, n)
+	dominators := make([]int, n)
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			if functions.Dominates(objectives[i], objectives[j]) {
+				dominated[i] = append(dominated[i], j)
+			} else if functions.Dominates(objectives[j], objectives[i]) {
+				dominators[i]++
+			}
+		}
+	}
+
+	front := []int{}
+	for i := 0; i < n; i++ {
+		if dominators[i] == 0 {
+			front = append(front, i)
+		}
+	}
+	for level := 0; len(front) > 0; level++ {
+		distances := crowding(objectives, front)
+		sort.SliceStable(front, func(a, b int) bool { return distances[front[a]] > distances[front[b]] })
+		order = append(order, front...)
+
+		next := []int{}
+		for _, i := range front {
+			fronts[i] = level
+			for _, j := range dominated[i] {
+				dominators[j]--
+				if dominators[j] == 0 {
+					next = append(next, j)
+				}
+			}
+		}
+		front = next
+	}
+	return
+}
+
+// The crowding distance of the points of a front, the sum over the objectives of the (normalized) distance
+// between the neighbours of a point; the extreme points of each objective are infinitely far
+func crowding(objectives [][]float64, front []int) map[int]float64 {
+
+	distances := make(map[int]float64, len(front))
+	if len(front) == 0 {
+		return distances
+	}
+
+	sorted := append([]int(nil), front...)
+	for m := range objectives[front[0]] {
+		sort.SliceStable(sorted, func(a, b int) bool { return objectives[sorted[a]][m] < objectives[sorted[b]][m] })
+		first, last := sorted[0], sorted[len(sorted)-1]
+		distances[first], distances[last] = math.Inf(1), math.Inf(1)
+		span := objectives[last][m] - objectives[first][m]
+		if span == 0 || math.IsInf(span, 0) || math.IsNaN(span) {
+			continue
+		}
+		for k := 1; k < len(sorted)-1; k++ {
+			distances[sorted[k]] += (objectives[sorted[k+1]][m] - objectives[sorted[k-1]][m]) / span
+		}
+	}
+	return distances
+}
@@ -0,0 +1,160 @@
+package generators
+
+import (
+	"github.com/acflorea/goptim/functions"
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+)
+
+// A point proposed by a population based search, with its value once evaluated
+type trial struct {
+	// The coordinates of the point in the unit hypercube
+	coordinates []float64
+	// The generation of the trial
+	generation int
+	// The position of the trial in its generation (-1 for the extra trials)
+	index int
+	value float64
+	// The values of all the objectives (multi-objective functions only)
+	values []float64
+}
+
+// The search behind a population based generator
+type evolution interface {
+	// The number of trials evaluated in each generation
+	populationSize() int
+	// The coordinates of the index-th trial of the current generation (index is -1 for an extra trial)
+	sample(index int, r *rand.Rand) []float64
+	// Learns from the trials of the current generation (sorted by value, best first) and moves to the next one
+	tell(trials []trial, r *rand.Rand)
+}
+
+// A generator that evaluates a population of points in generations
+// Each worker takes a slice of every generation (the trials w, w + cores, w + 2*cores ...).
+// A worker done with its slice gets extra trials of the same generation, so no worker waits for the others;
+// the generation ends as soon as populationSize trials are evaluated and late results are dropped.
+type populationGenerator struct {
+	// The generation strategy on each dimension
+	restrictions []GenerationStrategy
+	// Constraints between dimensions, each generated point satisfies all of them
+	constraints []Constraint
+	// The search strategy
+	evolution evolution
+	// How many points to generate per worker
+	pointsNo int
+	// The level of parallelism
+	cores int
+	// The current generation
+	generation *int
+	// The number of trials of the current generation taken by each worker
+	taken []int
+	// The evaluated trials of the current generation
+	results *[]trial
+	// The trials handed to each worker and not yet evaluated
+	pending [][]trial
+	// The number of results of each worker already recorded
+	told []int
+	// The number of points generated by each worker
+	index []int
+	// The random generator shared by the workers
+	r     *rand.Rand
+	mutex *sync.Mutex
+}
+
+func newPopulationGenerator(restrictions []GenerationStrategy, constraints []Constraint, evolution evolution,
+	pointsNo, cores int, seed int64) populationGenerator {
+	return populationGenerator{
+		restrictions: restrictions,
+		constraints:  constraints,
+		evolution:    evolution,
+		pointsNo:     pointsNo,
+		cores:        cores,
+		generation:   new(int),
+		taken:        make([]int, cores),
+		results:      &[]trial{},
+		pending:      make([][]trial, cores),
+		told:         make([]int, cores),
+		index:        make([]int, cores),
+		r:            rand.New(rand.NewSource(seed)),
+		mutex:        &sync.Mutex{},
+	}
+}
+
+func (g populationGenerator) Next(w int, initialState GeneratorState) (point functions.MultidimensionalPoint, state GeneratorState) {
+
+	state = initialState
+
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	// the outputs of the points generated by the worker, in order
+	for ; g.told[w] < len(state.Output) && len(g.pending[w]) > 0; g.told[w]++ {
+		result := g.pending[w][0]
+		g.pending[w] = g.pending[w][1:]
+		if result.generation != *g.generation {
+			continue
+		}
+		result.value = state.value(g.told[w])
+		if math.IsNaN(result.value) {
+			result.value = math.Inf(1)
+		}
+		if g.told[w] < len(state.Objectives) {
+			result.values = make([]float64, len(state.Objectives[g.told[w]]))
+			for i, value := range state.Objectives[g.told[w]] {
+				result.values[i] = value
+				if math.IsNaN(value) {
+					result.values[i] = math.Inf(1)
+				}
+			}
+		}
+		*g.results = append(*g.results, result)
+		if len(*g.results) >= g.evolution.populationSize() {
+			sort.SliceStable(*g.results, func(i, j int) bool { return (*g.results)[i].value < (*g.results)[j].value })
+			g.evolution.tell(*g.results, g.r)
+			*g.generation++
+			*g.results = nil
+			for worker := range g.taken {
+				g.taken[worker] = 0
+			}
+		}
+	}
+
+	next := trial{generation: *g.generation, index: -1}
+	if idx := w + g.taken[w]*g.cores; idx < g.evolution.populationSize() {
+		next.index = idx
+		g.taken[w]++
+	}
+	point = sampleFeasible(g.constraints, func() functions.MultidimensionalPoint {
+		next.coordinates = g.evolution.sample(next.index, g.r)
+		return unitToPoint(g.restrictions, next.coordinates)
+	})
+	g.pending[w] = append(g.pending[w], next)
+
+	state.GeneratedPoints = append(state.GeneratedPoints, point)
+
+	g.index[w]++
+
+	return
+}
+
+func (g populationGenerator) HasNext(w int) bool {
+	return g.index[w] < g.pointsNo
+}
+
+func (g populationGenerator) Improvement(state GeneratorState) bool {
+	return newBest(state)
+}
+
+// Folds a coordinate back into [0, 1) by reflecting it on the bounds
+func reflect(u float64) float64 {
+	u = math.Mod(u, 2)
+	if u < 0 {
+		u += 2
+	}
+	if u > 1 {
+		u = 2 - u
+	}
+	return math.Min(u, math.Nextafter(1, 0))
+}
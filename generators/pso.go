@@ -0,0 +1,218 @@
+package generators
+
+import (
+	"github.com/acflorea/goptim/functions"
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+)
+
+// The number of particles of each sub-swarm
+var PSOSwarmSize = 10
+
+// The inertia weight and the acceleration coefficients (cognitive and social) of the particles
+var PSOInertia, PSOCognitive, PSOSocial = 0.729, 1.49445, 1.49445
+
+// The maximum speed of a particle on each dimension, relative to the bounds of the dimension
+var PSOMaxVelocity = 0.2
+
+// The maximum velocity of a choice of a discrete dimension
+var PSOMaxChoiceVelocity = 4.0
+
+// A particle of a swarm
+// Positions are coordinates in the unit hypercube, the bounds of the dimensions are 0 and 1.
+// On discrete dimensions each choice has its own velocity, the next choice is drawn with probabilities
+// proportional to sigmoid(velocity) (a generalization of the binary PSO of Kennedy and Eberhart)
+type particle struct {
+	position []float64
+	velocity []float64
+	// The velocity of each choice of the discrete dimensions (nil for the other dimensions)
+	choiceVelocity [][]float64
+	// The best position found by the particle
+	best      []float64
+	bestValue float64
+}
+
+// Particle swarm optimization generator
+// Each worker moves its own sub-swarm, the particles are attracted by their own best position
+// and by the best position found by all the workers
+type psoGenerator struct {
+	// The generation strategy on each dimension
+	restrictions []GenerationStrategy
+	// Constraints between dimensions, each generated point satisfies all of them
+	constraints []Constraint
+	// How many points to generate per worker
+	pointsNo int
+	// The sub-swarm of each worker
+	swarms [][]particle
+	// The best position found by all the workers
+	best      *[]float64
+	bestValue *float64
+	// A random generator per worker
+	rngs []*rand.Rand
+	// The number of results of each worker already recorded
+	told []int
+	// The number of points generated by each worker
+	index []int
+	mutex *sync.Mutex
+}
+
+// Generates points by moving a swarm of particles, each worker owns a sub-swarm of PSOSwarmSize particles
+// The particles are evaluated in turns, starting from random positions
+func NewPSO(restrictions []GenerationStrategy, constraints []Constraint, pointsNo, cores int, seed int64) Generator {
+
+	rngs := make([]*rand.Rand, cores)
+	swarms := make([][]particle, cores)
+	for w := range rngs {
+		rngs[w] = rand.New(rand.NewSource(seed + int64(w)*1000003))
+		swarms[w] = make([]particle, PSOSwarmSize)
+	}
+	bestValue := math.Inf(1)
+
+	return psoGenerator{
+		restrictions: restrictions,
+		constraints:  constraints,
+		pointsNo:     pointsNo,
+		swarms:       swarms,
+		best:         &[]float64{},
+		bestValue:    &bestValue,
+		rngs:         rngs,
+		told:         make([]int, cores),
+		index:        make([]int, cores),
+		mutex:        &sync.Mutex{},
+	}
+}
+
+func (g psoGenerator) Next(w int, initialState GeneratorState) (point functions.MultidimensionalPoint, state GeneratorState) {
+
+	state = initialState
+
+	// the t-th point of the worker is the position of its (t % PSOSwarmSize)-th particle
+	swarm := g.swarms[w]
+	for ; g.told[w] < len(state.Output) && g.told[w] < g.index[w]; g.told[w]++ {
+		p := &swarm[g.told[w]%len(swarm)]
+		value := state.value(g.told[w])
+		if value < p.bestValue || p.best == nil {
+			p.best, p.bestValue = p.position, value
+		}
+		g.mutex.Lock()
+		if value < *g.bestValue {
+			*g.best, *g.bestValue = p.position, value
+		}
+		g.mutex.Unlock()
+	}
+
+	p := &swarm[g.index[w]%len(swarm)]
+	r := g.rngs[w]
+	attempts := 0
+	var next particle
+	point = sampleFeasible(g.constraints, func() functions.MultidimensionalPoint {
+		// a particle that keeps leaving the feasible region is moved to a random position
+		if p.best == nil || attempts >= MaxConstraintAttempts/2 {
+			next = g.initialize(*p, r)
+		} else {
+			next = g.move(*p, r)
+		}
+		attempts++
+		return unitToPoint(g.restrictions, next.position)
+	})
+	*p = next
+
+	state.GeneratedPoints = append(state.GeneratedPoints, point)
+
+	g.index[w]++
+
+	return
+}
+
+// The particle with a random position and velocity
+func (g psoGenerator) initialize(p particle, r *rand.Rand) particle {
+	p.position = randomCoordinates(len(g.restrictions), r)
+	p.velocity = make([]float64, len(g.restrictions))
+	p.choiceVelocity = make([][]float64, len(g.restrictions))
+	for dimIdx, restriction := range g.restrictions {
+		p.velocity[dimIdx] = PSOMaxVelocity * (2*r.Float64() - 1)
+		if restriction.Distribution == Discrete {
+			p.choiceVelocity[dimIdx] = make([]float64, len(restriction.ordered().Choices))
+		}
+	}
+	if p.best == nil {
+		p.bestValue = math.Inf(1)
+	}
+	return p
+}
+
+// The particle moved towards its best position and the global one
+func (g psoGenerator) move(p particle, r *rand.Rand) particle {
+
+	g.mutex.Lock()
+	globalBest := *g.best
+	g.mutex.Unlock()
+	if len(globalBest) == 0 {
+		globalBest = p.best
+	}
+
+	position, velocity := make([]float64, len(p.position)), make([]float64, len(p.velocity))
+	choiceVelocity := make([][]float64, len(p.choiceVelocity))
+	for dimIdx, restriction := range g.restrictions {
+
+		if restriction.Distribution == Discrete {
+			ordered := restriction.ordered()
+			current, own, global := choiceIndex(ordered, p.position[dimIdx]),
+				choiceIndex(ordered, p.best[dimIdx]), choiceIndex(ordered, globalBest[dimIdx])
+			weights := make([]float64, len(ordered.Choices))
+			choiceVelocity[dimIdx] = make([]float64, len(ordered.Choices))
+			total := 0.0
+			for choice := range weights {
+				v := PSOInertia*p.choiceVelocity[dimIdx][choice] +
+					PSOCognitive*r.Float64()*(indicator(choice == own)-indicator(choice == current)) +
+					PSOSocial*r.Float64()*(indicator(choice == global)-indicator(choice == current))
+				v = math.Max(-PSOMaxChoiceVelocity, math.Min(PSOMaxChoiceVelocity, v))
+				choiceVelocity[dimIdx][choice] = v
+				weights[choice] = 1 / (1 + math.Exp(-v))
+				total += weights[choice]
+			}
+			u := r.Float64() * total
+			next := 0
+			for ; next < len(weights)-1 && u >= weights[next]; next++ {
+				u -= weights[next]
+			}
+			position[dimIdx] = ordered.unitOf(ordered.Choices[next].Value)
+			continue
+		}
+
+		v := PSOInertia*p.velocity[dimIdx] +
+			PSOCognitive*r.Float64()*(p.best[dimIdx]-p.position[dimIdx]) +
+			PSOSocial*r.Float64()*(globalBest[dimIdx]-p.position[dimIdx])
+		v = math.Max(-PSOMaxVelocity, math.Min(PSOMaxVelocity, v))
+		x := p.position[dimIdx] + v
+		if x < 0 || x >= 1 {
+			// the particle stops at the bound
+			x, v = math.Max(0, math.Min(x, math.Nextafter(1, 0))), 0
+		}
+		position[dimIdx], velocity[dimIdx] = x, v
+	}
+	p.position, p.velocity, p.choiceVelocity = position, velocity, choiceVelocity
+	return p
+}
+
+// The index of the choice the coordinate maps to
+func choiceIndex(ordered GenerationStrategy, u float64) int {
+	return int(math.Min(float64(sort.SearchFloat64s(ordered.cdf, u)), float64(len(ordered.cdf)-1)))
+}
+
+func indicator(condition bool) float64 {
+	if condition {
+		return 1
+	}
+	return 0
+}
+
+func (g psoGenerator) HasNext(w int) bool {
+	return g.index[w] < g.pointsNo
+}
+
+func (g psoGenerator) Improvement(state GeneratorState) bool {
+	return newBest(state)
+}
@@ -0,0 +1,356 @@
+package generators
+
+import (
+	"fmt"
+	"github.com/acflorea/goptim/functions"
+	"math"
+	"math/rand"
+	"sync"
+)
+
+// A generator of points based on a (low discrepancy) sequence in the unit hypercube
+// The coordinates of each point are mapped through the distribution of the corresponding dimension
+type sequenceGenerator struct {
+	// The generation strategy on each dimension
+	restrictions []GenerationStrategy
+	// Constraints between dimensions, each generated point satisfies all of them
+	constraints []Constraint
+	// How many points to generate in total
+	pointsNo int
+	// The level of parallelism
+	cores int
+	// The parallel generation algorithm, decides which part of the sequence goes to which worker
+	algorithm Algorithm
+	// The coordinates of the index-th point of the sequence used by worker w
+	// (the workers share the same sequence, except for the Parametrization algorithm)
+	sequence func(w, index int) []float64
+	// The number of points generated by each worker
+	index []int
+	// The number of sequence points consumed by each worker (rejected points included)
+	position []int
+	// The next point of the shared sequence (ManagerWorker algorithm)
+	next  *int
+	mutex *sync.Mutex
+}
+
+func newSequenceGenerator(restrictions []GenerationStrategy, constraints []Constraint,
+	pointsNo, cores int, algorithm Algorithm, sequence func(w, index int) []float64) sequenceGenerator {
+	return sequenceGenerator{
+		restrictions: restrictions,
+		constraints:  constraints,
+		pointsNo:     pointsNo,
+		cores:        cores,
+		algorithm:    algorithm,
+		sequence:     sequence,
+		index:        make([]int, cores),
+		position:     make([]int, cores),
+		next:         new(int),
+		mutex:        &sync.Mutex{},
+	}
+}
+
+// The index (in the sequence) of the next point used by worker w
+func (g sequenceGenerator) nextIndex(w int) (index int) {
+	switch g.algorithm {
+	case ManagerWorker:
+		// a single sequence, the points are handed to the workers as they ask for them
+		g.mutex.Lock()
+		index = *g.next
+		*g.next++
+		g.mutex.Unlock()
+	case Leapfrog:
+		// every cores-th point
+		index = w + g.position[w]*g.cores
+	case SeqSplit:
+		// a contiguous block per worker, the points that replace the rejected ones come from past the end of the
+		// blocks (every cores-th one, so the workers do not share them)
+		block := g.pointsNo / g.cores
+		if g.position[w] < block {
+			index = w*block + g.position[w]
+		} else {
+			index = g.pointsNo + w + (g.position[w]-block)*g.cores
+		}
+	case Parametrization:
+		// each worker has its own sequence
+		index = g.position[w]
+	}
+	g.position[w]++
+	return
+}
+
+func (g sequenceGenerator) Next(w int, initialState GeneratorState) (point functions.MultidimensionalPoint, state GeneratorState) {
+
+	state = initialState
+
+	point = sampleFeasible(g.constraints, func() functions.MultidimensionalPoint {
+		return unitToPoint(g.restrictions, g.sequence(w, g.nextIndex(w)))
+	})
+
+	state.GeneratedPoints = append(state.GeneratedPoints, point)
+
+	g.index[w]++
+
+	return
+}
+
+func (g sequenceGenerator) HasNext(w int) bool {
+	if g.algorithm == ManagerWorker {
+		g.mutex.Lock()
+		defer g.mutex.Unlock()
+		return *g.next < g.pointsNo
+	}
+	return g.index[w] < g.pointsNo/g.cores
+}
+
+func (g sequenceGenerator) Improvement(state GeneratorState) bool {
+	return newBest(state)
+}
+
+// Maps the coordinates of a point in the unit hypercube to the values of the dimensions
+// (the coordinates of inactive conditional dimensions are ignored)
+func unitToPoint(restrictions []GenerationStrategy, coordinates []float64) functions.MultidimensionalPoint {
+	values := make(map[string]interface{})
+	for dimIdx, restriction := range restrictions {
+		if restriction.isActive(values) {
+			values[restriction.Label] = restriction.valueAt(coordinates[dimIdx])
+		}
+	}
+	return functions.MultidimensionalPoint{Values: values}
+}
+
+// Sobol sequence
+// Primitive polynomials (degree s and coefficients a) and initial direction numbers m
+// for the dimensions 2 to 21 (S. Joe and F. Y. Kuo, new-joe-kuo-6.21201)
+var sobolDirections = []struct {
+	s, a int
+	m    []uint32
+}{
+	{1, 0, []uint32{1}},
+	{2, 1, []uint32{1, 3}},
+	{3, 1, []uint32{1, 3, 1}},
+	{3, 2, []uint32{1, 1, 1}},
+	{4, 1, []uint32{1, 1, 3, 3}},
+	{4, 4, []uint32{1, 3, 5, 13}},
+	{5, 2, []uint32{1, 1, 5, 5, 17}},
+	{5, 4, []uint32{1, 1, 5, 5, 5}},
+	{5, 7, []uint32{1, 1, 7, 11, 19}},
+	{5, 11, []uint32{1, 1, 5, 1, 1}},
+	{5, 13, []uint32{1, 1, 1, 3, 11}},
+	{5, 14, []uint32{1, 3, 5, 5, 31}},
+	{6, 1, []uint32{1, 3, 3, 9, 7, 49}},
+	{6, 13, []uint32{1, 1, 1, 15, 21, 21}},
+	{6, 16, []uint32{1, 3, 1, 13, 27, 49}},
+	{6, 19, []uint32{1, 1, 1, 15, 7, 5}},
+	{6, 22, []uint32{1, 3, 1, 15, 13, 25}},
+	{6, 25, []uint32{1, 1, 5, 5, 19, 61}},
+	{7, 1, []uint32{1, 3, 7, 11, 23, 15, 103}},
+	{7, 4, []uint32{1, 3, 7, 13, 13, 15, 69}},
+}
+
+// The maximum number of dimensions supported by the Sobol generator
+var MaxSobolDimensions = len(sobolDirections) + 1
+
+const sobolBits = 32
+
+// The direction numbers of each dimension
+func sobolDirectionNumbers(dimensionsNo int) [][sobolBits]uint32 {
+	v := make([][sobolBits]uint32, dimensionsNo)
+	for dimIdx := 0; dimIdx < dimensionsNo; dimIdx++ {
+		if dimIdx == 0 {
+			// the first dimension is the van der Corput sequence in base 2
+			for k := 0; k < sobolBits; k++ {
+				v[dimIdx][k] = 1 << uint(sobolBits-1-k)
+			}
+			continue
+		}
+		d := sobolDirections[dimIdx-1]
+		for k := 0; k < sobolBits; k++ {
+			if k < d.s {
+				v[dimIdx][k] = d.m[k] << uint(sobolBits-1-k)
+			} else {
+				// v_k = a_1 v_{k-1} xor a_2 v_{k-2} ... xor v_{k-s} xor (v_{k-s} >> s)
+				v[dimIdx][k] = v[dimIdx][k-d.s] ^ (v[dimIdx][k-d.s] >> uint(d.s))
+				for j := 1; j < d.s; j++ {
+					if (d.a>>uint(d.s-1-j))&1 == 1 {
+						v[dimIdx][k] ^= v[dimIdx][k-j]
+					}
+				}
+			}
+		}
+	}
+	return v
+}
+
+// Generates points from the Sobol sequence
+// With the Parametrization algorithm each worker gets a randomly (digitally) shifted copy of the sequence
+func NewSobol(restrictions []GenerationStrategy,
+	constraints []Constraint,
+	pointsNo int,
+	cores int,
+	algorithm Algorithm,
+	seed int64) Generator {
+
+	dimensionsNo := len(restrictions)
+	if dimensionsNo > MaxSobolDimensions {
+		panic(fmt.Sprintf("The Sobol generator supports at most %d dimensions", MaxSobolDimensions))
+	}
+	v := sobolDirectionNumbers(dimensionsNo)
+
+	shifts := make([][]uint32, cores)
+	r := rand.New(rand.NewSource(seed))
+	for w := 0; w < cores; w++ {
+		shifts[w] = make([]uint32, dimensionsNo)
+		if algorithm == Parametrization {
+			for dimIdx := range shifts[w] {
+				shifts[w][dimIdx] = r.Uint32()
+			}
+		}
+	}
+
+	sequence := func(w, index int) []float64 {
+		// the first point of the sequence (the origin) is skipped
+		i := uint64(index + 1)
+		coordinates := make([]float64, dimensionsNo)
+		for dimIdx := 0; dimIdx < dimensionsNo; dimIdx++ {
+			x := shifts[w][dimIdx]
+			for k := 0; i>>uint(k) > 0 && k < sobolBits; k++ {
+				if (i>>uint(k))&1 == 1 {
+					x ^= v[dimIdx][k]
+				}
+			}
+			coordinates[dimIdx] = float64(x) / math.Exp2(sobolBits)
+		}
+		return coordinates
+	}
+
+	return newSequenceGenerator(restrictions, constraints, pointsNo, cores, algorithm, sequence)
+}
+
+// Generates points from the Halton sequence, scrambled with random permutations of the digits
+// With the Parametrization algorithm each worker gets its own permutations
+func NewHalton(restrictions []GenerationStrategy,
+	constraints []Constraint,
+	pointsNo int,
+	cores int,
+	algorithm Algorithm,
+	seed int64) Generator {
+
+	dimensionsNo := len(restrictions)
+	bases := primes(dimensionsNo)
+
+	// permutations[w][dimIdx] - the permutation of the digits in base bases[dimIdx]
+	permutations := make([][][]int, cores)
+	r := rand.New(rand.NewSource(seed))
+	for w := 0; w < cores; w++ {
+		if w > 0 && algorithm != Parametrization {
+			permutations[w] = permutations[0]
+			continue
+		}
+		permutations[w] = make([][]int, dimensionsNo)
+		for dimIdx, base := range bases {
+			// 0 stays in place, so the trailing zeros of the index don't contribute
+			permutation := append([]int{0}, r.Perm(base-1)...)
+			for k := 1; k < base; k++ {
+				permutation[k]++
+			}
+			permutations[w][dimIdx] = permutation
+		}
+	}
+
+	sequence := func(w, index int) []float64 {
+		// the first point of the sequence (the origin) is skipped
+		coordinates := make([]float64, dimensionsNo)
+		for dimIdx, base := range bases {
+			coordinates[dimIdx] = radicalInverse(index+1, base, permutations[w][dimIdx])
+		}
+		return coordinates
+	}
+
+	return newSequenceGenerator(restrictions, constraints, pointsNo, cores, algorithm, sequence)
+}
+
+// The digits of i in the given base, permuted and mirrored around the decimal point
+func radicalInverse(i, base int, permutation []int) float64 {
+	result := 0.0
+	factor := 1.0 / float64(base)
+	for ; i > 0; i /= base {
+		result += float64(permutation[i%base]) * factor
+		factor /= float64(base)
+	}
+	return result
+}
+
+// The first n prime numbers
+func primes(n int) []int {
+	result := []int{}
+	for candidate := 2; len(result) < n; candidate++ {
+		isPrime := true
+		for _, p := range result {
+			if p*p > candidate {
+				break
+			}
+			if candidate%p == 0 {
+				isPrime = false
+				break
+			}
+		}
+		if isPrime {
+			result = append(result, candidate)
+		}
+	}
+	return result
+}
+
+// Generates points from a Latin hypercube design of pointsNo points: each dimension is split into pointsNo
+// equally probable strata and each stratum is used exactly once
+// With the Parametrization algorithm each worker gets its own design (of pointsNo/cores points)
+// If the constraints reject some of the points new designs are created as needed
+func NewLatinHypercube(restrictions []GenerationStrategy,
+	constraints []Constraint,
+	pointsNo int,
+	cores int,
+	algorithm Algorithm,
+	seed int64) Generator {
+
+	dimensionsNo := len(restrictions)
+
+	designSize := pointsNo
+	if algorithm == Parametrization {
+		designSize = pointsNo / cores
+	}
+	designSize = int(math.Max(1, float64(designSize)))
+
+	// designs[w][k] - the k-th design used by worker w
+	designs := make([][][][]float64, cores)
+	var designsMutex sync.Mutex
+
+	design := func(w, k int) [][]float64 {
+		designsMutex.Lock()
+		defer designsMutex.Unlock()
+		for len(designs[w]) <= k {
+			// a new design, seeded by worker and design number
+			r := rand.New(rand.NewSource(seed + int64(w)*1000003 + int64(len(designs[w]))))
+			points := make([][]float64, designSize)
+			for i := range points {
+				points[i] = make([]float64, dimensionsNo)
+			}
+			for dimIdx := 0; dimIdx < dimensionsNo; dimIdx++ {
+				for i, stratum := range r.Perm(designSize) {
+					points[i][dimIdx] = (float64(stratum) + r.Float64()) / float64(designSize)
+				}
+			}
+			designs[w] = append(designs[w], points)
+		}
+		return designs[w][k]
+	}
+
+	sequence := func(w, index int) []float64 {
+		if algorithm != Parametrization {
+			// the workers share the designs
+			w = 0
+		}
+		return design(w, index/designSize)[index%designSize]
+	}
+
+	return newSequenceGenerator(restrictions, constraints, pointsNo, cores, algorithm, sequence)
+}
@@ -0,0 +1,258 @@
+package generators
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"strings"
+)
+
+// The description of a search space, as read from a JSON or YAML file
+// Eg: {"dimensions": [{"label": "attr_b", "distribution": "Uniform", "lower": -50, "upper": 50}]}
+type SpaceSpec struct {
+	Dimensions []DimensionSpec `json:"dimensions" yaml:"dimensions"`
+	// Constraints between dimensions, see ParseConstraint (Eg: "max_depth >= max_breadth")
+	Constraints []string `json:"constraints" yaml:"constraints"`
+}
+
+// The description of a single dimension
+type DimensionSpec struct {
+	Label string `json:"label" yaml:"label"`
+	// One of the keys of Distributions
+	Distribution string `json:"distribution" yaml:"distribution"`
+	// Bounds for uniform, quantized, logarithmic and truncated normal distributions
+	// (included for integers and quantized values)
+	Lower float64 `json:"lower" yaml:"lower"`
+	Upper float64 `json:"upper" yaml:"upper"`
+	// Step for quantized distributions
+	Step float64 `json:"step" yaml:"step"`
+	// Lambda for exponential distribution
+	Lambda float64 `json:"lambda" yaml:"lambda"`
+	// Mean and standard deviation for normal distributions (of the logarithm for LogNormal)
+	Mean   float64 `json:"mean" yaml:"mean"`
+	StdDev float64 `json:"stddev" yaml:"stddev"`
+	// Values for discrete distribution
+	Values []interface{} `json:"values" yaml:"values"`
+	// Weights of the discrete values (all values are equally likely if missing)
+	Probabilities []float64 `json:"probabilities" yaml:"probabilities"`
+	// Weight used by the WRS to decide if the value changes, on any scale (e.g. percentages) as the weights are
+	// normalized; if missing it is the largest weight of the other dimensions (1.0 if none has one)
+	ProbabilityToChange *float64 `json:"probabilityToChange" yaml:"probabilityToChange"`
+	// If specified the dimension is only generated when the condition holds
+	Condition *ConditionSpec `json:"condition" yaml:"condition"`
+}
+
+// The dimension is active only when the parent dimension has one of the values
+// Eg: {"parent": "kernel", "values": [1]}
+type ConditionSpec struct {
+	Parent string        `json:"parent" yaml:"parent"`
+	Values []interface{} `json:"values" yaml:"values"`
+}
+
+// Reads a search space file and builds the restrictions, constraints and probabilities to change out of it
+// The format is decided based on the file extension (.yaml or .yml for YAML, JSON otherwise)
+func LoadSpace(fileName string) (restrictions []GenerationStrategy, constraints []Constraint,
+	probabilityToChange []float64, err error) {
+
+	content, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	spec := SpaceSpec{}
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(content, &spec)
+	default:
+		decoder := json.NewDecoder(bytes.NewReader(content))
+		// keep integers as integers
+		decoder.UseNumber()
+		err = decoder.Decode(&spec)
+	}
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("invalid search space file %s: %v", fileName, err)
+	}
+
+	return spec.Build()
+}
+
+// Builds the restrictions, constraints and probabilities to change described by the spec
+func (spec SpaceSpec) Build() (restrictions []GenerationStrategy, constraints []Constraint,
+	probabilityToChange []float64, err error) {
+
+	if len(spec.Dimensions) == 0 {
+		return nil, nil, nil, fmt.Errorf("the search space has no dimensions")
+	}
+
+	labels := make(map[string]bool)
+	// the largest of the given probabilities to change, the default of the missing ones
+	maxProbability, missing := 0.0, false
+	for _, dimension := range spec.Dimensions {
+
+		if dimension.Label == "" {
+			return nil, nil, nil, fmt.Errorf("dimension without label")
+		}
+		if labels[dimension.Label] {
+			return nil, nil, nil, fmt.Errorf("duplicate dimension %s", dimension.Label)
+		}
+		labels[dimension.Label] = true
+
+		restriction, err := dimension.build()
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		if condition := dimension.Condition; condition != nil {
+			if !labels[condition.Parent] || condition.Parent == dimension.Label {
+				return nil, nil, nil, fmt.Errorf("dimension %s: the parent %s has to be defined before the dimension",
+					dimension.Label, condition.Parent)
+			}
+			if len(condition.Values) == 0 {
+				return nil, nil, nil, fmt.Errorf("dimension %s: the condition has no values", dimension.Label)
+			}
+			values := make([]interface{}, len(condition.Values))
+			for idx, value := range condition.Values {
+				values[idx] = specValue(value)
+			}
+			restriction = restriction.When(condition.Parent, values...)
+		}
+		restrictions = append(restrictions, restriction)
+
+		if probability := dimension.ProbabilityToChange; probability != nil {
+			if *probability < 0 {
+				return nil, nil, nil, fmt.Errorf("dimension %s: negative probability to change", dimension.Label)
+			}
+			probabilityToChange = append(probabilityToChange, *probability)
+			maxProbability = math.Max(maxProbability, *probability)
+		} else {
+			probabilityToChange = append(probabilityToChange, -1)
+			missing = true
+		}
+	}
+
+	if missing {
+		if maxProbability == 0 {
+			maxProbability = 1.0
+		}
+		for idx := range probabilityToChange {
+			if probabilityToChange[idx] < 0 {
+				probabilityToChange[idx] = maxProbability
+			}
+		}
+	}
+
+	for _, expression := range spec.Constraints {
+		constraint, constraintLabels, err := parseConstraint(expression)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		for _, label := range constraintLabels {
+			if !labels[label] {
+				return nil, nil, nil, fmt.Errorf("constraint %q: unknown dimension %s", expression, label)
+			}
+		}
+		constraints = append(constraints, constraint)
+	}
+
+	return restrictions, constraints, probabilityToChange, nil
+}
+
+func (dimension DimensionSpec) build() (GenerationStrategy, error) {
+
+	distribution, ok := Distributions[dimension.Distribution]
+	if !ok {
+		return GenerationStrategy{}, fmt.Errorf("dimension %s: unknown distribution %q",
+			dimension.Label, dimension.Distribution)
+	}
+
+	switch distribution {
+	case Uniform:
+		if dimension.Lower >= dimension.Upper {
+			return GenerationStrategy{}, fmt.Errorf("dimension %s: lower bound must be smaller than the upper bound",
+				dimension.Label)
+		}
+		return NewUniform(dimension.Label, dimension.Lower, dimension.Upper), nil
+	case IntUniform:
+		if dimension.Lower > dimension.Upper {
+			return GenerationStrategy{}, fmt.Errorf("dimension %s: lower bound must not exceed the upper bound",
+				dimension.Label)
+		}
+		return NewIntUniform(dimension.Label, int(dimension.Lower), int(dimension.Upper)), nil
+	case QUniform:
+		if dimension.Step <= 0 || dimension.Lower > dimension.Upper {
+			return GenerationStrategy{}, fmt.Errorf("dimension %s: step must be positive and the lower bound "+
+				"must not exceed the upper bound", dimension.Label)
+		}
+		return NewQUniform(dimension.Label, dimension.Lower, dimension.Upper, dimension.Step), nil
+	case LogUniform, LogInteger:
+		if dimension.Lower <= 0 || dimension.Lower >= dimension.Upper {
+			return GenerationStrategy{}, fmt.Errorf("dimension %s: bounds must be positive and the lower bound "+
+				"smaller than the upper bound", dimension.Label)
+		}
+		if distribution == LogInteger {
+			return NewLogInteger(dimension.Label, int(dimension.Lower), int(dimension.Upper)), nil
+		}
+		return NewLogUniform(dimension.Label, dimension.Lower, dimension.Upper), nil
+	case Normal, TruncatedNormal, LogNormal:
+		if dimension.StdDev <= 0 {
+			return GenerationStrategy{}, fmt.Errorf("dimension %s: stddev must be positive", dimension.Label)
+		}
+		switch distribution {
+		case TruncatedNormal:
+			if dimension.Lower >= dimension.Upper {
+				return GenerationStrategy{}, fmt.Errorf("dimension %s: lower bound must be smaller than the upper bound",
+					dimension.Label)
+			}
+			return NewTruncatedNormal(dimension.Label, dimension.Mean, dimension.StdDev,
+				dimension.Lower, dimension.Upper), nil
+		case LogNormal:
+			return NewLogNormal(dimension.Label, dimension.Mean, dimension.StdDev), nil
+		}
+		return NewNormal(dimension.Label, dimension.Mean, dimension.StdDev), nil
+	case Exponential:
+		if dimension.Lambda <= 0 {
+			return GenerationStrategy{}, fmt.Errorf("dimension %s: lambda must be positive", dimension.Label)
+		}
+		return NewExponential(dimension.Label, dimension.Lambda), nil
+	case Discrete:
+		if len(dimension.Values) == 0 {
+			return GenerationStrategy{}, fmt.Errorf("dimension %s: no values", dimension.Label)
+		}
+		if len(dimension.Probabilities) > 0 && len(dimension.Probabilities) != len(dimension.Values) {
+			return GenerationStrategy{}, fmt.Errorf("dimension %s: %d values but %d probabilities",
+				dimension.Label, len(dimension.Values), len(dimension.Probabilities))
+		}
+		choices := make([]DiscreteChoice, len(dimension.Values))
+		for idx, value := range dimension.Values {
+			choices[idx] = DiscreteChoice{specValue(value), 1.0}
+			if len(dimension.Probabilities) > 0 {
+				choices[idx].Probability = dimension.Probabilities[idx]
+			}
+		}
+		return NewOrderedDiscrete(dimension.Label, choices), nil
+	}
+
+	return GenerationStrategy{}, fmt.Errorf("dimension %s: unsupported distribution %q",
+		dimension.Label, dimension.Distribution)
+}
+
+// Converts a value read from a file to the types the target functions expect (int, float64 or string)
+func specValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return int(i)
+		}
+		if f, err := v.Float64(); err == nil {
+			return f
+		}
+		return v.String()
+	case int64:
+		return int(v)
+	}
+	return value
+}
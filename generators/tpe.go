@@ -0,0 +1,237 @@
+package generators
+
+import (
+	"github.com/acflorea/goptim/functions"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// The fraction of the trials considered good by the TPE generator
+var TPEGamma = 0.25
+
+// The number of candidates drawn from the density of the good trials for each TPE point
+var TPECandidates = 24
+
+// Tree-structured Parzen Estimator generator
+// The trials are split in good and bad ones and each dimension gets two density estimates, l for the good values
+// and g for the bad ones. The next point is the candidate (drawn from l) with the highest l/g ratio.
+type tpeGenerator struct {
+	// The generation strategy on each dimension
+	restrictions []GenerationStrategy
+	// Constraints between dimensions, each generated point satisfies all of them
+	constraints []Constraint
+	// The number of random points generated before the densities are used
+	initialPointsNo int
+	// How many points to generate per worker
+	pointsNo int
+	// The trials seen so far
+	history *history
+	// A random generator per worker
+	rngs []*rand.Rand
+	// The number of points generated by each worker
+	index []int
+}
+
+// Generates points with the Tree-structured Parzen Estimator
+// The first initialPointsNo points are random
+// Discrete dimensions have categorical densities, the others Parzen estimators (mixtures of truncated normals)
+// over the position of the values in the cumulative distribution function.
+// A conditional dimension learns only from the trials in which it is active.
+func NewTPE(restrictions []GenerationStrategy, constraints []Constraint,
+	initialPointsNo, pointsNo, cores int, algorithm Algorithm, seed int64) Generator {
+
+	rngs := make([]*rand.Rand, cores)
+	for w := range rngs {
+		rngs[w] = rand.New(rand.NewSource(seed + int64(w)*1000003))
+	}
+
+	return &tpeGenerator{
+		restrictions:    restrictions,
+		constraints:     constraints,
+		initialPointsNo: initialPointsNo,
+		pointsNo:        pointsNo,
+		history:         newHistory(cores, algorithm),
+		rngs:            rngs,
+		index:           make([]int, cores),
+	}
+}
+
+func (g *tpeGenerator) Next(w int, initialState GeneratorState) (point functions.MultidimensionalPoint, state GeneratorState) {
+
+	state = initialState
+
+	points, outputs := g.history.update(w, state)
+	if len(points) < g.initialPointsNo || len(points) < 2 {
+		point = randomPoint(g.restrictions, g.constraints, g.rngs[w])
+	} else {
+		point = g.propose(points, outputs, g.rngs[w])
+	}
+
+	state.GeneratedPoints = append(state.GeneratedPoints, point)
+
+	g.index[w]++
+
+	return
+}
+
+func (g *tpeGenerator) HasNext(w int) bool {
+	return g.index[w] < g.pointsNo
+}
+
+func (g *tpeGenerator) Improvement(state GeneratorState) bool {
+	return newBest(state)
+}
+
+// The feasible candidate with the highest l/g ratio
+func (g *tpeGenerator) propose(points []functions.MultidimensionalPoint, outputs []float64,
+	r *rand.Rand) functions.MultidimensionalPoint {
+
+	order := make([]int, len(points))
+	for idx := range order {
+		order[idx] = idx
+	}
+	sort.SliceStable(order, func(i, j int) bool { return outputs[order[i]] < outputs[order[j]] })
+	goodNo := int(math.Ceil(TPEGamma * float64(len(points))))
+
+	good := make([]functions.MultidimensionalPoint, goodNo)
+	bad := make([]functions.MultidimensionalPoint, len(points)-goodNo)
+	for rank, idx := range order {
+		if rank < goodNo {
+			good[rank] = points[idx]
+		} else {
+			bad[rank-goodNo] = points[idx]
+		}
+	}
+
+	l := make([]parzenEstimator, len(g.restrictions))
+	gb := make([]parzenEstimator, len(g.restrictions))
+	for dimIdx, restriction := range g.restrictions {
+		l[dimIdx] = newParzenEstimator(restriction, good)
+		gb[dimIdx] = newParzenEstimator(restriction, bad)
+	}
+
+	var proposal functions.MultidimensionalPoint
+	proposalScore := math.Inf(-1)
+	for c := 0; c < TPECandidates; c++ {
+		values := make(map[string]interface{})
+		score := 0.0
+		for dimIdx, restriction := range g.restrictions {
+			if !restriction.isActive(values) {
+				continue
+			}
+			value := l[dimIdx].sample(r)
+			values[restriction.Label] = value
+			score += math.Log(l[dimIdx].density(value)) - math.Log(gb[dimIdx].density(value))
+		}
+		candidate := functions.MultidimensionalPoint{Values: values}
+		if Feasible(candidate, g.constraints) && score > proposalScore {
+			proposal, proposalScore = candidate, score
+		}
+	}
+
+	if proposal.Values == nil {
+		// no feasible candidate, fall back to rejection sampling
+		return randomPoint(g.restrictions, g.constraints, r)
+	}
+	return proposal
+}
+
+// The density of the values of a dimension, estimated from a set of trials
+// It is a mixture of the prior (the distribution of the dimension) and one kernel per observed value
+type parzenEstimator struct {
+	restriction GenerationStrategy
+	// The positions of the observed values in [0, 1] and the kernel bandwidth (continuous dimensions)
+	centers   []float64
+	bandwidth float64
+	// The weight of each choice (discrete dimensions)
+	weights map[interface{}]float64
+}
+
+func newParzenEstimator(restriction GenerationStrategy, points []functions.MultidimensionalPoint) parzenEstimator {
+
+	estimator := parzenEstimator{restriction: restriction}
+
+	if restriction.Distribution == Discrete {
+		// the prior counts as one observation
+		ordered := restriction.ordered()
+		estimator.weights = make(map[interface{}]float64)
+		for _, choice := range ordered.Choices {
+			estimator.weights[choice.Value] += choice.Probability
+		}
+		total := 1.0
+		for _, point := range points {
+			if value, ok := point.Values[restriction.Label]; ok {
+				estimator.weights[value]++
+				total++
+			}
+		}
+		for value := range estimator.weights {
+			estimator.weights[value] /= total
+		}
+		return estimator
+	}
+
+	for _, point := range points {
+		if value, ok := point.Values[restriction.Label]; ok {
+			estimator.centers = append(estimator.centers, restriction.unitOf(value))
+		}
+	}
+
+	// Scott's rule, bounded so that the kernels get narrower only as the observations add up
+	// and never wider than the interval
+	n := float64(len(estimator.centers))
+	mean, variance := 0.0, 0.0
+	for _, center := range estimator.centers {
+		mean += center / n
+	}
+	for _, center := range estimator.centers {
+		variance += (center - mean) * (center - mean) / n
+	}
+	estimator.bandwidth = math.Min(math.Max(1.06*math.Sqrt(variance)*math.Pow(n+1, -0.2), 1/math.Min(100, n+1)), 1)
+
+	return estimator
+}
+
+// Draws a value from the estimated density
+func (e parzenEstimator) sample(r *rand.Rand) interface{} {
+
+	if e.weights != nil {
+		u := r.Float64()
+		for _, choice := range e.restriction.ordered().Choices {
+			if u -= e.weights[choice.Value]; u < 0 {
+				return choice.Value
+			}
+		}
+		// rounding errors, the last choice
+		choices := e.restriction.ordered().Choices
+		return choices[len(choices)-1].Value
+	}
+
+	// the prior is the uniform density on [0, 1], as the values are positions in the distribution
+	component := r.Intn(len(e.centers) + 1)
+	if component == len(e.centers) {
+		return e.restriction.valueAt(r.Float64())
+	}
+	center := e.centers[component]
+	pa, pb := normalCDF(-center/e.bandwidth), normalCDF((1-center)/e.bandwidth)
+	u := center + e.bandwidth*normalQuantile(pa+(pb-pa)*r.Float64())
+	return e.restriction.valueAt(math.Min(math.Max(u, 0), math.Nextafter(1, 0)))
+}
+
+// The density of the value
+func (e parzenEstimator) density(value interface{}) float64 {
+
+	if e.weights != nil {
+		return e.weights[value]
+	}
+
+	x := e.restriction.unitOf(value)
+	density := 1.0
+	for _, center := range e.centers {
+		pa, pb := normalCDF(-center/e.bandwidth), normalCDF((1-center)/e.bandwidth)
+		z := (x - center) / e.bandwidth
+		density += math.Exp(-z*z/2) / math.Sqrt(2*math.Pi) / e.bandwidth / (pb - pa)
+	}
+	return density / float64(len(e.centers)+1)
+}
@@ -5,9 +5,16 @@ type Distribution int
 
 // Map with distribution by name
 var Distributions = map[string]Distribution{
-	"Uniform":     Uniform,
-	"Exponential": Exponential,
-	"Discrete":    Discrete,
+	"Uniform":         Uniform,
+	"Exponential":     Exponential,
+	"Discrete":        Discrete,
+	"LogUniform":      LogUniform,
+	"LogInteger":      LogInteger,
+	"IntUniform":      IntUniform,
+	"QUniform":        QUniform,
+	"Normal":          Normal,
+	"TruncatedNormal": TruncatedNormal,
+	"LogNormal":       LogNormal,
 }
 
 // Types of distributions
@@ -18,6 +25,20 @@ const (
 	Exponential
 	// Discrete
 	Discrete
+	// Uniform on a logarithmic scale
+	LogUniform
+	// Integer values, uniform on a logarithmic scale
+	LogInteger
+	// Integer values, uniform
+	IntUniform
+	// Values on a grid with a fixed step, uniform
+	QUniform
+	// Normal (Gaussian)
+	Normal
+	// Normal, restricted to an interval
+	TruncatedNormal
+	// Normal on a logarithmic scale
+	LogNormal
 )
 
 // Algorithm labels
@@ -25,6 +46,13 @@ var distributions = [...]string{
 	"Uiform",
 	"Exponential",
 	"Discrete",
+	"LogUniform",
+	"LogInteger",
+	"IntUniform",
+	"QUniform",
+	"Normal",
+	"TruncatedNormal",
+	"LogNormal",
 }
 
 // The random generation algorithm
@@ -57,3 +85,134 @@ var algorithms = [...]string{
 	"SeqSplit",
 	"Parametrization",
 }
+
+// The point generation strategy
+type Sampler int
+
+// Map with samplers by name
+var Samplers = map[string]Sampler{
+	"Random":          Random,
+	"Sobol":           Sobol,
+	"Halton":          Halton,
+	"LatinHypercube":  LatinHypercube,
+	"Grid":            Grid,
+	"GaussianProcess": GaussianProcess,
+	"TPE":             TPE,
+	"CMAES":           CMAES,
+	"DE":              DifferentialEvolution,
+	"GA":              GeneticAlgorithm,
+	"PSO":             PSO,
+	"Annealing":       Annealing,
+	"NSGA2":           NSGA2,
+}
+
+// Types of samplers
+const (
+	// (Weighted) random search
+	Random Sampler = iota
+	// Sobol low discrepancy sequence
+	Sobol
+	// Scrambled Halton low discrepancy sequence
+	Halton
+	// Latin hypercube design for a fixed number of points
+	LatinHypercube
+	// Exhaustive search over a grid
+	Grid
+	// Bayesian optimization with a gaussian process model
+	GaussianProcess
+	// Tree-structured Parzen Estimator
+	TPE
+	// Covariance Matrix Adaptation Evolution Strategy
+	CMAES
+	// Differential evolution
+	DifferentialEvolution
+	// Genetic algorithm
+	GeneticAlgorithm
+	// Particle swarm optimization
+	PSO
+	// Simulated annealing
+	Annealing
+	// Non-dominated sorting genetic algorithm, for multi-objective functions
+	NSGA2
+)
+
+// The way the weighted random search sets the probability to change of each dimension
+type Adaptation int
+
+// Map with adaptations by name
+var Adaptations = map[string]Adaptation{
+	"Fixed":      FixedProbabilities,
+	"Importance": ImportanceProbabilities,
+	"OneFifth":   OneFifthRule,
+	"Bandit":     BanditProbabilities,
+}
+
+// Types of adaptations
+const (
+	// The probabilities given to the generator, for the whole run
+	FixedProbabilities Adaptation = iota
+	// The importance of the dimensions, estimated at the end of the tuning phase
+	ImportanceProbabilities
+	// Each probability grows when changing the dimension leads to a new best point and shrinks otherwise,
+	// so that about one step in five that changes it is a success
+	OneFifthRule
+	// The probabilities follow the success rate of the steps that changed each dimension (UCB1)
+	BanditProbabilities
+)
+
+// The way the temperature of simulated annealing decreases
+type CoolingSchedule int
+
+// Map with cooling schedules by name
+var CoolingSchedules = map[string]CoolingSchedule{
+	"Exponential": ExponentialCooling,
+	"Linear":      LinearCooling,
+	"Logarithmic": LogarithmicCooling,
+}
+
+// Types of cooling schedules
+const (
+	// T0 * alpha^k, with alpha such that the last temperature is 1000 times lower than the first one
+	ExponentialCooling CoolingSchedule = iota
+	// T0 * (1 - k/n)
+	LinearCooling
+	// T0 * log(2) / log(k + 2)
+	LogarithmicCooling
+)
+
+// The way differential evolution builds the trials
+type DEStrategy int
+
+// Map with differential evolution strategies by name
+var DEStrategies = map[string]DEStrategy{
+	"rand/1/bin": Rand1Bin,
+	"best/1/bin": Best1Bin,
+}
+
+// Types of differential evolution strategies
+const (
+	// A random individual plus the scaled difference of two others, binomial crossover
+	Rand1Bin DEStrategy = iota
+	// The best individual plus the scaled difference of two others, binomial crossover
+	Best1Bin
+)
+
+// The criterion used by the model based generators to pick the next point
+type Acquisition int
+
+// Map with acquisition functions by name
+var Acquisitions = map[string]Acquisition{
+	"EI":  ExpectedImprovement,
+	"UCB": UpperConfidenceBound,
+	"PI":  ProbabilityOfImprovement,
+}
+
+// Types of acquisition functions
+const (
+	// The expected amount by which the point improves the best value
+	ExpectedImprovement Acquisition = iota
+	// An optimistic estimate of the value (the bound of the confidence interval on the side of improvement)
+	UpperConfidenceBound
+	// The probability that the point improves the best value
+	ProbabilityOfImprovement
+)
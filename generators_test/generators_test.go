@@ -1,6 +1,10 @@
 package generators_test
 
 import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 	"github.com/acflorea/goptim/generators"
 	"fmt"
@@ -55,11 +59,11 @@ func Test_DiscreteGenerate(t *testing.T) {
 	}
 
 	generator :=
-		generators.NewRandom(restrictions, howManyPoints, 1, generators.ManagerWorker)
+		generators.NewRandom(restrictions, nil, []float64{}, generators.FixedProbabilities, false, 100.0, howManyPoints, howManyPoints, 1, generators.ManagerWorker, time.Now().UnixNano())
 
 	generatedPoints := make([]functions.MultidimensionalPoint, howManyPoints)
 	for pIdx := 0; generator.HasNext(0); pIdx++ {
-		generatedPoints[pIdx] = generator.Next(0)
+		generatedPoints[pIdx], _ = generator.Next(0, generators.GeneratorState{})
 	}
 
 	counts := []int{
@@ -155,16 +159,16 @@ func Test_UniformRandomPointsGeneratorNext(t *testing.T) {
 	}
 
 	generator :=
-		generators.NewRandom(restrictions, howManyPoints, 1, generators.ManagerWorker)
+		generators.NewRandom(restrictions, nil, []float64{}, generators.FixedProbabilities, false, 100.0, howManyPoints, howManyPoints, 1, generators.ManagerWorker, time.Now().UnixNano())
 
 	generatedPoints := make([]functions.MultidimensionalPoint, howManyPoints)
 	for pIdx := 0; generator.HasNext(0); pIdx++ {
-		generatedPoints[pIdx] = generator.Next(0)
+		generatedPoints[pIdx], _ = generator.Next(0, generators.GeneratorState{})
 	}
 
 	if len(generatedPoints) != howManyPoints {
 		msg := fmt.Sprintf("Error generating points. "+
-			"Expected (%i) but got (%i).", howManyPoints, len(generatedPoints))
+			"Expected (%d) but got (%d).", howManyPoints, len(generatedPoints))
 		t.Error(msg)
 	}
 
@@ -189,13 +193,17 @@ func Test_UniformRandomPointsGeneratorAll(t *testing.T) {
 	}
 
 	generator :=
-		generators.NewRandom(restrictions, howManyPoints, 1, generators.ManagerWorker)
+		generators.NewRandom(restrictions, nil, []float64{}, generators.FixedProbabilities, false, 100.0, howManyPoints, howManyPoints, 1, generators.ManagerWorker, time.Now().UnixNano())
 
-	generatedPoints := generator.AllAvailable(0)
+	generatedPoints := []functions.MultidimensionalPoint{}
+	for generator.HasNext(0) {
+		point, _ := generator.Next(0, generators.GeneratorState{})
+		generatedPoints = append(generatedPoints, point)
+	}
 
 	if len(generatedPoints) != howManyPoints {
 		msg := fmt.Sprintf("Error generating points. "+
-			"Expected (%i) but got (%i).", howManyPoints, len(generatedPoints))
+			"Expected (%d) but got (%d).", howManyPoints, len(generatedPoints))
 		t.Error(msg)
 	}
 
@@ -209,3 +217,1100 @@ func Test_UniformRandomPointsGeneratorAll(t *testing.T) {
 		}
 	}
 }
+
+func Test_LogFloat64(t *testing.T) {
+
+	source := rand.NewSource(time.Now().UnixNano())
+	r := rand.New(source)
+
+	count := 1000000
+	a := 1e-5
+	b := 1e5
+	// on a logarithmic scale a decade is as likely as any other
+	belowOne := 0
+	for i := 0; i < count; i++ {
+		_, value := generators.LogFloat64(a, b, r)
+		if value < a || value >= b {
+			t.Error("Value out of bounds", value)
+		}
+		if value < 1 {
+			belowOne++
+		}
+	}
+	if math.Abs(float64(belowOne)/float64(count)-0.5) > 0.01 {
+		t.Error("The generated values don't look log-uniform - values below 1 are", float64(belowOne)/float64(count))
+	}
+
+}
+
+func Test_LogInt(t *testing.T) {
+
+	source := rand.NewSource(time.Now().UnixNano())
+	r := rand.New(source)
+
+	counts := make(map[int]int)
+	for i := 0; i < 100000; i++ {
+		_, value := generators.LogInt(1, 10, r)
+		if value < 1 || value > 10 {
+			t.Error("Value out of bounds", value)
+		}
+		counts[value]++
+	}
+	if len(counts) != 10 {
+		t.Error("Not all the values in the range were generated", counts)
+	}
+	if counts[1] <= counts[10] {
+		t.Error("Small values should be more likely than large ones", counts)
+	}
+
+}
+
+func Test_Int(t *testing.T) {
+
+	source := rand.NewSource(time.Now().UnixNano())
+	r := rand.New(source)
+
+	counts := make(map[int]int)
+	for i := 0; i < 100000; i++ {
+		_, value := generators.Int(1, 55, r)
+		if value < 1 || value > 55 {
+			t.Error("Value out of bounds", value)
+		}
+		counts[value]++
+	}
+	if len(counts) != 55 {
+		t.Error("Not all the values in the range were generated", len(counts))
+	}
+
+}
+
+func Test_QFloat64(t *testing.T) {
+
+	source := rand.NewSource(time.Now().UnixNano())
+	r := rand.New(source)
+
+	counts := make(map[float64]int)
+	for i := 0; i < 100000; i++ {
+		_, value := generators.QFloat64(-1, 1, 0.1, r)
+		if value < -1 || value > 1 {
+			t.Error("Value out of bounds", value)
+		}
+		counts[value]++
+	}
+	// -1.0, -0.9 ... 0.9, 1.0
+	if len(counts) != 21 {
+		t.Error("Expected 21 distinct values but got", len(counts), counts)
+	}
+	if counts[0.3] == 0 || counts[-0.7] == 0 || counts[1] == 0 {
+		t.Error("The values are not snapped to the grid", counts)
+	}
+
+}
+
+func Test_NormFloat64(t *testing.T) {
+
+	source := rand.NewSource(time.Now().UnixNano())
+	r := rand.New(source)
+
+	count := 1000000
+	values := make([]float64, count)
+	expectedMean := 3.0
+	expectedVariance := 0.25
+	for i := 0; i < count; i++ {
+		_, values[i] = generators.NormFloat64(expectedMean, math.Sqrt(expectedVariance), r)
+	}
+	mean := 0.0
+	for i := 0; i < count; i++ {
+		mean += values[i] / float64(count)
+	}
+	variance := 0.0
+	for i := 0; i < count; i++ {
+		variance += math.Pow((values[i] - mean), 2) / float64(count)
+	}
+	if math.Abs(mean-expectedMean) > 0.01 {
+		t.Error("The generated values don't look normal - the mean is different.", mean, expectedMean)
+	}
+	if math.Abs(variance-expectedVariance) > 0.01 {
+		t.Error("The generated values don't look normal - the variance is different", variance, expectedVariance)
+	}
+
+}
+
+func Test_TruncNormFloat64(t *testing.T) {
+
+	source := rand.NewSource(time.Now().UnixNano())
+	r := rand.New(source)
+
+	count := 100000
+	mean := 0.0
+	for i := 0; i < count; i++ {
+		_, value := generators.TruncNormFloat64(0, 1, 0, 10, r)
+		if value < 0 || value > 10 {
+			t.Error("Value out of bounds", value)
+		}
+		mean += value / float64(count)
+	}
+	// the mean of the half normal distribution is sqrt(2/pi)
+	if math.Abs(mean-math.Sqrt(2/math.Pi)) > 0.01 {
+		t.Error("The generated values don't look half normal - the mean is different.", mean)
+	}
+
+}
+
+func Test_ConditionalDimensions(t *testing.T) {
+
+	howManyPoints := 1000
+
+	kernels := map[interface{}]float64{0: 1, 1: 1, 2: 1}
+	restrictions := []generators.GenerationStrategy{
+		generators.NewDiscrete("kernel", kernels),
+		generators.NewLogUniform("C", 1e-5, 1e5),
+		generators.NewIntUniform("degree", 2, 5).When("kernel", 1),
+		generators.NewLogUniform("gamma", 1e-5, 1e5).When("kernel", 1, 2),
+	}
+
+	generator :=
+		generators.NewRandom(restrictions, nil, []float64{1.0, 0.5, 0.5, 0.5}, generators.FixedProbabilities, false, 100.0, howManyPoints, 10, 1, generators.ManagerWorker, time.Now().UnixNano())
+
+	state := generators.GeneratorState{}
+	for generator.HasNext(0) {
+		var point functions.MultidimensionalPoint
+		point, state = generator.Next(0, state)
+		state.Output = append(state.Output, rand.Float64())
+		state.Centroid = point
+
+		kernel := point.Values["kernel"]
+		_, hasDegree := point.Values["degree"]
+		_, hasGamma := point.Values["gamma"]
+		if hasDegree != (kernel == 1) {
+			t.Error("degree should be generated only for the polynomial kernel", point.PrettyPrint())
+		}
+		if hasGamma != (kernel == 1 || kernel == 2) {
+			t.Error("gamma should be generated only for the polynomial and rbf kernels", point.PrettyPrint())
+		}
+	}
+
+}
+
+func Test_Constraints(t *testing.T) {
+
+	howManyPoints := 1000
+
+	restrictions := []generators.GenerationStrategy{
+		generators.NewIntUniform("max_breadth", 1, 55),
+		generators.NewIntUniform("max_depth", 2, 55),
+		generators.NewQUniform("attr_b", -50, 50, 0.1),
+		generators.NewQUniform("attr_c", -1, 1, 0.1),
+	}
+
+	depth, err := generators.ParseConstraint("max_depth >= max_breadth")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sign, err := generators.ParseConstraint("attr_b * attr_c < 0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	constraints := []generators.Constraint{depth, sign}
+
+	generator :=
+		generators.NewRandom(restrictions, constraints, []float64{}, generators.FixedProbabilities, false, 100.0, howManyPoints, 10, 1, generators.ManagerWorker, time.Now().UnixNano())
+
+	state := generators.GeneratorState{}
+	for generator.HasNext(0) {
+		var point functions.MultidimensionalPoint
+		point, state = generator.Next(0, state)
+		state.Output = append(state.Output, rand.Float64())
+		state.Centroid = point
+
+		if point.Values["max_depth"].(int) < point.Values["max_breadth"].(int) ||
+			point.Values["attr_b"].(float64)*point.Values["attr_c"].(float64) >= 0 {
+			t.Error("Infeasible point generated", point.PrettyPrint())
+		}
+	}
+
+}
+
+func Test_InfeasibleConstraints(t *testing.T) {
+
+	restrictions := []generators.GenerationStrategy{
+		generators.NewUniform("x", 0, 1),
+	}
+	never, err := generators.ParseConstraint("x > 2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	generator :=
+		generators.NewRandom(restrictions, []generators.Constraint{never}, []float64{}, generators.FixedProbabilities, false, 100.0, 1, 1, 1, generators.ManagerWorker, time.Now().UnixNano())
+
+	defer func() {
+		err, _ := recover().(error)
+		if !errors.Is(err, generators.ErrInfeasible) {
+			t.Error("An empty feasible region should be reported", err)
+		}
+	}()
+	generator.Next(0, generators.GeneratorState{})
+
+}
+
+func Test_ParseConstraint(t *testing.T) {
+
+	valid := map[string]bool{
+		"x >= y":          true,
+		"x * (y - 2) < 1": true,
+		"-x + 3 / y != 0": true,
+		"x >":             false,
+		"x + y":           false,
+		"x = y":           false,
+		"(x < y":          false,
+	}
+	for expression, expected := range valid {
+		_, err := generators.ParseConstraint(expression)
+		if (err == nil) != expected {
+			t.Error("Unexpected parsing result for", expression, err)
+		}
+	}
+
+	constraint, _ := generators.ParseConstraint("x * (y - 2) < 1")
+	point := functions.MultidimensionalPoint{Values: map[string]interface{}{"x": 1.0, "y": 2}}
+	if !constraint(point) {
+		t.Error("1 * (2 - 2) < 1 should hold")
+	}
+	point = functions.MultidimensionalPoint{Values: map[string]interface{}{"x": 1.0, "y": 4}}
+	if constraint(point) {
+		t.Error("1 * (4 - 2) < 1 should not hold")
+	}
+	point = functions.MultidimensionalPoint{Values: map[string]interface{}{"x": 1.0}}
+	if !constraint(point) {
+		t.Error("A constraint over a missing dimension should hold")
+	}
+
+}
+
+func Test_DiscreteOrder(t *testing.T) {
+
+	// the same values, inserted in different orders
+	first := make(map[interface{}]float64)
+	second := make(map[interface{}]float64)
+	keys := []interface{}{"b", 10, 2.5, "a", 1, 1.0}
+	for idx := range keys {
+		first[keys[idx]] = float64(idx + 1)
+		second[keys[len(keys)-1-idx]] = float64(len(keys) - idx)
+	}
+
+	firstG := generators.NewDiscrete("X", first)
+	secondG := generators.NewDiscrete("X", second)
+
+	expected := []interface{}{1.0, 1, 2.5, 10, "a", "b"}
+	for idx, choice := range firstG.Choices {
+		if choice.Value != expected[idx] || secondG.Choices[idx] != choice {
+			t.Error("Unexpected order of the discrete values", firstG.Choices, secondG.Choices)
+			break
+		}
+	}
+
+	ordered := generators.NewOrderedDiscrete("X", []generators.DiscreteChoice{{Value: "z", Probability: 1}, {Value: "y", Probability: 3}})
+	if ordered.Choices[0].Value != "z" || ordered.Choices[1].Probability != 0.75 {
+		t.Error("The order of the choices is not preserved", ordered.Choices)
+	}
+
+}
+
+func Test_SeededGenerator(t *testing.T) {
+
+	howManyPoints := 100
+	cores := 4
+
+	restrictions := []generators.GenerationStrategy{
+		generators.NewUniform("x", -10, 10),
+		generators.NewIntUniform("y", 1, 55),
+		generators.NewDiscrete("z", map[interface{}]float64{"a": 1, "b": 2, "c": 3}),
+	}
+
+	for _, algorithm := range generators.Algorithms {
+		if algorithm == generators.ManagerWorker {
+			// the generator is shared, the order in which the workers use it matters
+			continue
+		}
+
+		points := make([][]functions.MultidimensionalPoint, 2)
+		for run := 0; run < 2; run++ {
+			generator := generators.NewRandom(restrictions, nil, []float64{1, 0.5, 0.25}, generators.FixedProbabilities,
+				false, 100.0, howManyPoints, 10, cores, algorithm, 42)
+			for w := 0; w < cores; w++ {
+				state := generators.GeneratorState{}
+				for i := 0; i < howManyPoints/cores; i++ {
+					var point functions.MultidimensionalPoint
+					point, state = generator.Next(w, state)
+					state.Output = append(state.Output, float64(i%7))
+					state.Centroid = state.GeneratedPoints[0]
+					points[run] = append(points[run], point)
+				}
+			}
+		}
+
+		for idx := range points[0] {
+			if points[0][idx].PrettyPrint() != points[1][idx].PrettyPrint() {
+				t.Error("The same seed generated different points", algorithm,
+					points[0][idx].PrettyPrint(), points[1][idx].PrettyPrint())
+				break
+			}
+		}
+	}
+
+}
+
+// Generates all the points of worker 0
+func allPoints(generator generators.Generator) []functions.MultidimensionalPoint {
+	points := []functions.MultidimensionalPoint{}
+	state := generators.GeneratorState{}
+	for generator.HasNext(0) {
+		var point functions.MultidimensionalPoint
+		point, state = generator.Next(0, state)
+		points = append(points, point)
+	}
+	return points
+}
+
+// Checks that each of the bins (equal intervals of [0, 1)) contains at most one value of the dimension
+func stratified(points []functions.MultidimensionalPoint, label string, bins int) bool {
+	used := make(map[int]bool)
+	for _, point := range points {
+		bin := int(point.Values[label].(float64) * float64(bins))
+		if used[bin] {
+			return false
+		}
+		used[bin] = true
+	}
+	return true
+}
+
+func Test_Sobol(t *testing.T) {
+
+	restrictions := []generators.GenerationStrategy{}
+	for dimIdx := 0; dimIdx < generators.MaxSobolDimensions; dimIdx++ {
+		restrictions = append(restrictions, generators.NewUniform(fmt.Sprintf("x%d", dimIdx), 0, 1))
+	}
+
+	// the origin is skipped, so 1023 points fill 1023 of the 1024 bins
+	points := allPoints(generators.NewSobol(restrictions, nil, 1023, 1, generators.SeqSplit, 0))
+	if len(points) != 1023 {
+		t.Fatal("Unexpected number of points", len(points))
+	}
+	for _, restriction := range restrictions {
+		if !stratified(points, restriction.Label, 1024) {
+			t.Error("The Sobol sequence is not stratified on", restriction.Label)
+		}
+	}
+
+	expected := [][]float64{{0.5, 0.5}, {0.25, 0.75}, {0.75, 0.25}, {0.125, 0.625}}
+	for idx, coordinates := range expected {
+		if points[idx].Values["x0"] != coordinates[0] || points[idx].Values["x1"] != coordinates[1] {
+			t.Error("Unexpected Sobol point", idx, points[idx].PrettyPrint())
+		}
+	}
+
+}
+
+func Test_Halton(t *testing.T) {
+
+	restrictions := []generators.GenerationStrategy{
+		generators.NewUniform("x", 0, 1),
+		generators.NewUniform("y", 0, 1),
+		generators.NewUniform("z", 0, 1),
+	}
+
+	points := allPoints(generators.NewHalton(restrictions, nil, 124, 1, generators.SeqSplit, 42))
+	// 2^7, 3^5, 5^3
+	if !stratified(points, "x", 128) || !stratified(points, "y", 243) || !stratified(points, "z", 125) {
+		t.Error("The Halton sequence is not stratified")
+	}
+
+}
+
+func Test_LatinHypercube(t *testing.T) {
+
+	restrictions := []generators.GenerationStrategy{
+		generators.NewUniform("x", 0, 1),
+		generators.NewUniform("y", 0, 1),
+	}
+
+	cores := 4
+	generator := generators.NewLatinHypercube(restrictions, nil, 100, cores, generators.Leapfrog, 42)
+	points := []functions.MultidimensionalPoint{}
+	for w := 0; w < cores; w++ {
+		state := generators.GeneratorState{}
+		for generator.HasNext(w) {
+			var point functions.MultidimensionalPoint
+			point, state = generator.Next(w, state)
+			points = append(points, point)
+		}
+	}
+
+	if len(points) != 100 {
+		t.Fatal("Unexpected number of points", len(points))
+	}
+	if !stratified(points, "x", 100) || !stratified(points, "y", 100) {
+		t.Error("Each stratum should be used exactly once")
+	}
+
+}
+
+func Test_Grid(t *testing.T) {
+
+	restrictions := []generators.GenerationStrategy{
+		generators.NewDiscrete("kernel", map[interface{}]float64{"linear": 1, "rbf": 1}),
+		generators.NewIntUniform("degree", 2, 4).When("kernel", "rbf"),
+		generators.NewQUniform("c", 0, 1, 0.5),
+		generators.NewUniform("gamma", 0, 1),
+	}
+	constraint, _ := generators.ParseConstraint("c + gamma < 1.5")
+
+	// linear: 3 * 4 points, rbf: 3 * 3 * 4 points, minus those with c = 1 and gamma > 0.5
+	expected := 3*4 + 3*3*4 - 2 - 3*2
+
+	for _, algorithm := range []generators.Algorithm{generators.ManagerWorker, generators.Leapfrog,
+		generators.SeqSplit, generators.Parametrization} {
+
+		cores := 3
+		generator := generators.NewGrid(restrictions, []generators.Constraint{constraint}, 4, cores, algorithm)
+
+		points := make(map[string]bool)
+		for w := 0; w < cores; w++ {
+			state := generators.GeneratorState{}
+			for generator.HasNext(w) {
+				var point functions.MultidimensionalPoint
+				point, state = generator.Next(w, state)
+				if !constraint(point) {
+					t.Error("The point violates the constraint", point.PrettyPrint())
+				}
+				if _, ok := point.Values["degree"]; ok != (point.Values["kernel"] == "rbf") {
+					t.Error("Unexpected conditional value", point.PrettyPrint())
+				}
+				if points[point.PrettyPrint()] {
+					t.Error("Duplicate point", point.PrettyPrint())
+				}
+				points[point.PrettyPrint()] = true
+			}
+		}
+
+		if len(points) != expected {
+			t.Error("Unexpected number of points", algorithm, len(points), expected)
+		}
+	}
+
+	// concurrent workers share the last points without running out of the grid
+	cores := 8
+	generator := generators.NewGrid(restrictions, []generators.Constraint{constraint}, 4, cores, generators.ManagerWorker)
+	counts := make(chan int, cores)
+	for w := 0; w < cores; w++ {
+		go func(w int) {
+			count := 0
+			state := generators.GeneratorState{}
+			for generator.HasNext(w) {
+				var point functions.MultidimensionalPoint
+				point, state = generator.Next(w, state)
+				if len(point.Values) == 0 {
+					t.Error("Empty grid point")
+				}
+				count++
+			}
+			counts <- count
+		}(w)
+	}
+	total := 0
+	for w := 0; w < cores; w++ {
+		total += <-counts
+	}
+	if total != expected {
+		t.Error("Unexpected number of points", total, expected)
+	}
+
+}
+
+// Minimizes f with the generator (a single worker) and returns the best point and value
+func minimize(generator generators.Generator, f func(values map[string]interface{}) float64) (
+	functions.MultidimensionalPoint, float64) {
+
+	state := generators.GeneratorState{}
+	var best functions.MultidimensionalPoint
+	min := math.MaxFloat64
+	for generator.HasNext(0) {
+		var point functions.MultidimensionalPoint
+		point, state = generator.Next(0, state)
+		if len(point.Values) == 0 {
+			// a refinement that converged
+			break
+		}
+		value := f(point.Values)
+		state.Output = append(state.Output, value)
+		if value < min {
+			best, min = point, value
+		}
+	}
+	return best, min
+}
+
+func Test_GaussianProcess(t *testing.T) {
+
+	restrictions := []generators.GenerationStrategy{
+		generators.NewUniform("x", 0, 1),
+		generators.NewLogUniform("y", 0.001, 1000),
+		generators.NewDiscrete("kernel", map[interface{}]float64{"linear": 1, "poly": 1, "rbf": 1}),
+	}
+	f := func(values map[string]interface{}) float64 {
+		x, y := values["x"].(float64), math.Log10(values["y"].(float64))
+		penalty := 0.0
+		if values["kernel"] != "rbf" {
+			penalty = 1
+		}
+		return (x-0.3)*(x-0.3) + (y-1)*(y-1)/36 + penalty
+	}
+
+	for name, acquisition := range generators.Acquisitions {
+		generator := generators.NewGaussianProcess(restrictions, nil, acquisition, 10, 40, 1,
+			generators.SeqSplit, 42)
+		best, min := minimize(generator, f)
+		if min > 0.01 || best.Values["kernel"] != "rbf" {
+			t.Error(name, "did not find the minimum", best.PrettyPrint(), min)
+		}
+	}
+
+}
+
+func Test_TPE(t *testing.T) {
+
+	restrictions := []generators.GenerationStrategy{
+		generators.NewDiscrete("kernel", map[interface{}]float64{"linear": 1, "poly": 1, "rbf": 1}),
+		generators.NewUniform("x", 0, 1),
+		generators.NewIntUniform("degree", 1, 10).When("kernel", "poly"),
+	}
+	f := func(values map[string]interface{}) float64 {
+		x := values["x"].(float64)
+		switch values["kernel"] {
+		case "poly":
+			degree := float64(values["degree"].(int))
+			return (x-0.7)*(x-0.7) + (degree-3)*(degree-3)/100
+		case "rbf":
+			return 0.2 + (x-0.7)*(x-0.7)
+		}
+		return 0.5
+	}
+
+	generator := generators.NewTPE(restrictions, nil, 10, 100, 1, generators.SeqSplit, 42)
+	best, min := minimize(generator, f)
+	if min > 0.01 || best.Values["degree"] != 3 {
+		t.Error("TPE did not find the minimum", best.PrettyPrint(), min)
+	}
+
+}
+
+func Test_CMAES(t *testing.T) {
+
+	restrictions := []generators.GenerationStrategy{}
+	for dimIdx := 0; dimIdx < 4; dimIdx++ {
+		restrictions = append(restrictions, generators.NewUniform(fmt.Sprintf("x%d", dimIdx), -5, 5))
+	}
+	// a rotated ellipsoid
+	f := func(values map[string]interface{}) float64 {
+		sum := 0.0
+		for dimIdx := range restrictions {
+			partial := 0.0
+			for j := 0; j <= dimIdx; j++ {
+				partial += values[fmt.Sprintf("x%d", j)].(float64) - 1
+			}
+			sum += partial * partial
+		}
+		return sum
+	}
+
+	generator := generators.NewCMAES(restrictions, nil, true, 1000, 1, 42)
+	if _, min := minimize(generator, f); min > 1e-6 {
+		t.Error("CMA-ES did not find the minimum", min)
+	}
+
+	// the workers share the generations
+	cores := 4
+	generator = generators.NewCMAES(restrictions, nil, true, 250, cores, 42)
+	done := make(chan float64, cores)
+	for w := 0; w < cores; w++ {
+		go func(w int) {
+			state := generators.GeneratorState{}
+			min := math.MaxFloat64
+			for generator.HasNext(w) {
+				var point functions.MultidimensionalPoint
+				point, state = generator.Next(w, state)
+				value := f(point.Values)
+				state.Output = append(state.Output, value)
+				min = math.Min(min, value)
+			}
+			done <- min
+		}(w)
+	}
+	min := math.MaxFloat64
+	for w := 0; w < cores; w++ {
+		min = math.Min(min, <-done)
+	}
+	if min > 1e-6 {
+		t.Error("CMA-ES did not find the minimum with", cores, "workers", min)
+	}
+
+}
+
+func Test_Evolutionary(t *testing.T) {
+
+	restrictions := []generators.GenerationStrategy{
+		generators.NewUniform("x", -5, 5),
+		generators.NewUniform("y", -5, 5),
+		generators.NewIntUniform("n", 0, 20),
+		generators.NewDiscrete("kernel", map[interface{}]float64{"linear": 1, "poly": 1, "rbf": 1}),
+	}
+	f := func(values map[string]interface{}) float64 {
+		x, y, n := values["x"].(float64), values["y"].(float64), float64(values["n"].(int))
+		value := (x-1)*(x-1) + (y+2)*(y+2) + (n-7)*(n-7)
+		if values["kernel"] != "rbf" {
+			value += 1
+		}
+		return value
+	}
+
+	searches := map[string]generators.Generator{
+		"rand/1/bin": generators.NewDifferentialEvolution(restrictions, nil, generators.Rand1Bin, 2000, 1, 42),
+		"best/1/bin": generators.NewDifferentialEvolution(restrictions, nil, generators.Best1Bin, 2000, 1, 42),
+		"GA":         generators.NewGeneticAlgorithm(restrictions, nil, 2000, 1, 42),
+	}
+	for name, generator := range searches {
+		best, min := minimize(generator, f)
+		if min > 0.01 || best.Values["n"] != 7 || best.Values["kernel"] != "rbf" {
+			t.Error(name, "did not find the minimum", best.PrettyPrint(), min)
+		}
+	}
+
+}
+
+func Test_PSO(t *testing.T) {
+
+	restrictions := []generators.GenerationStrategy{
+		generators.NewUniform("x", -5, 5),
+		generators.NewUniform("y", -5, 5),
+		generators.NewDiscrete("kernel", map[interface{}]float64{"linear": 1, "poly": 1, "rbf": 1}),
+	}
+	f := func(values map[string]interface{}) float64 {
+		x, y := values["x"].(float64), values["y"].(float64)
+		value := (x-1)*(x-1) + (y+2)*(y+2)
+		if values["kernel"] != "rbf" {
+			value += 1
+		}
+		return value
+	}
+
+	cores := 4
+	generator := generators.NewPSO(restrictions, nil, 300, cores, 42)
+	type result struct {
+		point functions.MultidimensionalPoint
+		value float64
+	}
+	done := make(chan result, cores)
+	for w := 0; w < cores; w++ {
+		go func(w int) {
+			state := generators.GeneratorState{}
+			best := result{value: math.MaxFloat64}
+			for generator.HasNext(w) {
+				var point functions.MultidimensionalPoint
+				point, state = generator.Next(w, state)
+				value := f(point.Values)
+				state.Output = append(state.Output, value)
+				if value < best.value {
+					best = result{point, value}
+				}
+			}
+			done <- best
+		}(w)
+	}
+	// the sub-swarms share the global best, so every worker ends close to the minimum
+	for w := 0; w < cores; w++ {
+		if best := <-done; best.value > 0.01 || best.point.Values["kernel"] != "rbf" {
+			t.Error("PSO did not find the minimum", best.point.PrettyPrint(), best.value)
+		}
+	}
+
+}
+
+func Test_Annealing(t *testing.T) {
+
+	restrictions := []generators.GenerationStrategy{
+		generators.NewUniform("x", -5, 5),
+		generators.NewIntUniform("n", 0, 100),
+		generators.NewQUniform("q", 0, 10, 0.5),
+		generators.NewDiscrete("kernel", map[interface{}]float64{"linear": 1, "poly": 1, "rbf": 1}),
+	}
+	f := func(values map[string]interface{}) float64 {
+		x, n, q := values["x"].(float64), float64(values["n"].(int)), values["q"].(float64)
+		value := (x-1)*(x-1) + (n-70)*(n-70)/100 + (q-3.5)*(q-3.5)
+		if values["kernel"] != "rbf" {
+			value += 1
+		}
+		return value
+	}
+
+	for name, schedule := range generators.CoolingSchedules {
+		generator := generators.NewAnnealing(restrictions, nil, schedule, 0.1, 2000, 1, 42)
+		best, min := minimize(generator, f)
+		if min > 0.01 || best.Values["n"] != 70 || best.Values["q"] != 3.5 || best.Values["kernel"] != "rbf" {
+			t.Error(name, "did not find the minimum", best.PrettyPrint(), min)
+		}
+	}
+
+}
+
+func Test_NelderMead(t *testing.T) {
+
+	restrictions := []generators.GenerationStrategy{
+		generators.NewUniform("x", -5, 5),
+		generators.NewUniform("y", -5, 5),
+		generators.NewDiscrete("kernel", map[interface{}]float64{"linear": 1, "rbf": 1}),
+	}
+	f := func(values map[string]interface{}) float64 {
+		x, y := values["x"].(float64), values["y"].(float64)
+		return 100*(y-x*x)*(y-x*x) + (1-x)*(1-x)
+	}
+
+	generator := generators.NewRandom(restrictions, nil, []float64{1, 1, 1}, generators.FixedProbabilities, false, 100,
+		10, 1, 1, generators.SeqSplit, 42)
+	start := functions.MultidimensionalPoint{Values: map[string]interface{}{"x": -1.0, "y": 2.0, "kernel": "rbf"}}
+	refiner := generator.(generators.Refinable).Refine(start, f(start.Values), 1000)
+
+	best, min := minimize(refiner, f)
+	if min > 1e-4 || best.Values["kernel"] != "rbf" {
+		t.Error("Nelder-Mead did not find the minimum", best.PrettyPrint(), min)
+	}
+	if refiner.HasNext(0) {
+		t.Error("Nelder-Mead should be over once the simplex converged")
+	}
+
+	// nothing to refine
+	discrete := []generators.GenerationStrategy{generators.NewIntUniform("n", 0, 10)}
+	generator = generators.NewRandom(discrete, nil, []float64{1}, generators.FixedProbabilities, false, 100, 10, 1, 1,
+		generators.SeqSplit, 42)
+	start = functions.MultidimensionalPoint{Values: map[string]interface{}{"n": 3}}
+	if generator.(generators.Refinable).Refine(start, 0, 1000) != nil {
+		t.Error("Only continuous dimensions are refined")
+	}
+
+}
+
+func Test_Importance(t *testing.T) {
+
+	restrictions := []generators.GenerationStrategy{
+		generators.NewUniform("x", 0, 1),
+		generators.NewUniform("y", 0, 1),
+		generators.NewDiscrete("z", map[interface{}]float64{"a": 1, "b": 1}),
+	}
+	f := func(values map[string]interface{}) float64 {
+		x, y := values["x"].(float64), values["y"].(float64)
+		return 10*(x-0.5)*(x-0.5) + y
+	}
+
+	r := rand.New(rand.NewSource(42))
+	points, outputs := []functions.MultidimensionalPoint{}, []float64{}
+	generator := generators.NewLatinHypercube(restrictions, nil, 200, 1, generators.SeqSplit, 42)
+	state := generators.GeneratorState{}
+	for generator.HasNext(0) {
+		var point functions.MultidimensionalPoint
+		point, state = generator.Next(0, state)
+		points, outputs = append(points, point), append(outputs, f(point.Values))
+	}
+
+	importance := generators.Importance(restrictions, points, outputs, r)
+	// the variance of 10(x-1/2)^2 is 80/9 times the one of y
+	if importance[0] < 0.7 || importance[1] < 0.03 || importance[1] > 0.3 || importance[2] > 0.03 {
+		t.Error("Unexpected importance", importance)
+	}
+
+	// after the tuning phase the irrelevant dimension rarely changes
+	generator = generators.NewRandom(restrictions, nil, []float64{1, 1, 1}, generators.ImportanceProbabilities, false,
+		100, 300, 100, 1, generators.SeqSplit, 42)
+	state = generators.GeneratorState{}
+	changes := map[string]int{}
+	min := math.MaxFloat64
+	for i := 0; generator.HasNext(0); i++ {
+		var point functions.MultidimensionalPoint
+		point, state = generator.Next(0, state)
+		if i >= 100 {
+			for label, value := range point.Values {
+				if state.Centroid.Values[label] != value {
+					changes[label]++
+				}
+			}
+		}
+		value := f(point.Values)
+		state.Output = append(state.Output, value)
+		if value < min {
+			min, state.Centroid = value, point
+		}
+	}
+	if changes["x"] < 150 || changes["z"] > 30 {
+		t.Error("The important dimensions should change more often", changes)
+	}
+
+}
+
+func Test_AdaptiveProbabilities(t *testing.T) {
+
+	restrictions := []generators.GenerationStrategy{
+		generators.NewUniform("x", 0, 1),
+		generators.NewUniform("y", 0, 1),
+		generators.NewDiscrete("z", map[interface{}]float64{"a": 1, "b": 1}),
+	}
+	f := func(values map[string]interface{}) float64 {
+		x, y := values["x"].(float64), values["y"].(float64)
+		return (x-0.3)*(x-0.3) + (y-0.6)*(y-0.6)
+	}
+
+	for name, adaptation := range generators.Adaptations {
+		generator := generators.NewRandom(restrictions, nil, []float64{1, 0.5}, adaptation, false,
+			100, 200, 20, 1, generators.SeqSplit, 42)
+		state := generators.GeneratorState{}
+		min := math.MaxFloat64
+		for generator.HasNext(0) {
+			var point functions.MultidimensionalPoint
+			point, state = generator.Next(0, state)
+			value := f(point.Values)
+			state.Output = append(state.Output, value)
+			if value < min {
+				min, state.Centroid = value, point
+			}
+		}
+
+		if len(state.Probabilities) != len(state.GeneratedPoints) {
+			t.Fatal(name, "The probabilities of each point should be recorded", len(state.Probabilities))
+		}
+		last := state.Probabilities[len(state.Probabilities)-1]
+		switch adaptation {
+		case generators.FixedProbabilities:
+			if len(last) != 2 || last[0] != 1 || last[1] != 0.5 {
+				t.Error(name, "The fixed probabilities should not change", last)
+			}
+		case generators.OneFifthRule, generators.BanditProbabilities:
+			if len(last) != 3 || last[0] == 1 && last[1] == 0.5 && last[2] == 1 {
+				t.Error(name, "The probabilities should adapt", last)
+			}
+			for _, p := range last {
+				if p < generators.MinProbabilityToChange || p > 1 {
+					t.Error(name, "The probabilities should stay in range", last)
+				}
+			}
+		}
+		if min > 0.01 {
+			t.Error(name, "The search should get close to the optimum", min)
+		}
+	}
+
+}
+
+func Test_PrunedOutputs(t *testing.T) {
+
+	restrictions := []generators.GenerationStrategy{generators.NewUniform("x", 0, 1)}
+	generator := generators.NewRandom(restrictions, nil, []float64{1}, generators.FixedProbabilities, false, 10.0,
+		10, 1, 1, generators.SeqSplit, 42)
+
+	points := []functions.MultidimensionalPoint{{Values: map[string]interface{}{"x": 0.1}},
+		{Values: map[string]interface{}{"x": 0.2}}}
+	state := generators.GeneratorState{GeneratedPoints: points, Output: []float64{5, 1}}
+	if !generator.Improvement(state) {
+		t.Error("The last point should be an improvement")
+	}
+
+	// the partial value of a pruned trial is not an improvement
+	state.Pruned = []int{1}
+	if generator.Improvement(state) {
+		t.Error("A pruned point should not be an improvement")
+	}
+
+}
+
+func Test_DominanceImprovement(t *testing.T) {
+
+	restrictions := []generators.GenerationStrategy{generators.NewUniform("x", 0, 1)}
+	generator := generators.NewRandom(restrictions, nil, []float64{1}, generators.FixedProbabilities, false, 100.0,
+		10, 1, 1, generators.SeqSplit, 42)
+
+	points := []functions.MultidimensionalPoint{{Values: map[string]interface{}{"x": 0.1}},
+		{Values: map[string]interface{}{"x": 0.2}}, {Values: map[string]interface{}{"x": 0.3}}}
+
+	// the last point trades the first objective for the second one
+	state := generators.GeneratorState{GeneratedPoints: points, Output: []float64{1, 3, 2},
+		Objectives: [][]float64{{1, 3}, {3, 1}, {2, 2}}}
+	if !generator.Improvement(state) {
+		t.Error("A non-dominated point should be an improvement")
+	}
+
+	// the last point is worse than the first one on both objectives
+	state.Output = []float64{1, 3, 2}
+	state.Objectives = [][]float64{{1, 1}, {3, 0}, {2, 2}}
+	if generator.Improvement(state) {
+		t.Error("A dominated point should not be an improvement")
+	}
+
+}
+
+func Test_SequenceConstraints(t *testing.T) {
+
+	restrictions := []generators.GenerationStrategy{
+		generators.NewUniform("x", 0, 1),
+		generators.NewUniform("y", 0, 1),
+	}
+	constraint, err := generators.ParseConstraint("x + y < 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	constraints := []generators.Constraint{constraint}
+
+	cores := 2
+	for name, generator := range map[string]generators.Generator{
+		"Sobol":  generators.NewSobol(restrictions, constraints, 64, cores, generators.SeqSplit, 42),
+		"Halton": generators.NewHalton(restrictions, constraints, 64, cores, generators.SeqSplit, 42),
+	} {
+		// the rejected points of a worker are not replaced by the points of the other worker
+		points := make(map[string]bool)
+		for w := 0; w < cores; w++ {
+			state := generators.GeneratorState{}
+			for generator.HasNext(w) {
+				var point functions.MultidimensionalPoint
+				point, state = generator.Next(w, state)
+				if points[point.PrettyPrint()] {
+					t.Error(name, "Duplicate point", point.PrettyPrint())
+				}
+				points[point.PrettyPrint()] = true
+			}
+		}
+		if len(points) != 64 {
+			t.Error(name, "Unexpected number of points", len(points))
+		}
+	}
+
+}
+
+func Test_SpaceProbabilityToChange(t *testing.T) {
+
+	weight := func(w float64) *float64 { return &w }
+	spec := generators.SpaceSpec{Dimensions: []generators.DimensionSpec{
+		{Label: "x", Distribution: "Uniform", Lower: 0, Upper: 1, ProbabilityToChange: weight(15.06)},
+		{Label: "y", Distribution: "Uniform", Lower: 0, Upper: 1},
+		{Label: "z", Distribution: "Uniform", Lower: 0, Upper: 1, ProbabilityToChange: weight(7.76)},
+	}}
+
+	// the missing weight is on the scale of the given ones
+	_, _, probabilities, err := spec.Build()
+	if err != nil || fmt.Sprint(probabilities) != "[15.06 15.06 7.76]" {
+		t.Error("The missing probability to change should be the largest one", probabilities, err)
+	}
+
+	spec.Dimensions[0].ProbabilityToChange, spec.Dimensions[2].ProbabilityToChange = nil, nil
+	if _, _, probabilities, _ = spec.Build(); fmt.Sprint(probabilities) != "[1 1 1]" {
+		t.Error("Without weights all the probabilities to change should be 1", probabilities)
+	}
+
+	spec.Dimensions[1].ProbabilityToChange = weight(-1)
+	if _, _, _, err = spec.Build(); err == nil {
+		t.Error("A negative probability to change should be rejected")
+	}
+
+}
+
+func Test_LoadSpace(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "space")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"space.json": `{"dimensions": [
+			{"label": "x", "distribution": "Uniform", "lower": -5, "upper": 5, "probabilityToChange": 0.5},
+			{"label": "kernel", "distribution": "Discrete", "values": [0, 1, 2], "probabilities": [1, 1, 2]},
+			{"label": "degree", "distribution": "IntUniform", "lower": 2, "upper": 5,
+				"condition": {"parent": "kernel", "values": [1]}}],
+			"constraints": ["x < 2 * degree"]}`,
+		"space.yaml": `
+dimensions:
+  - label: x
+    distribution: Uniform
+    lower: -5
+    upper: 5
+    probabilityToChange: 0.5
+  - label: kernel
+    distribution: Discrete
+    values: [0, 1, 2]
+    probabilities: [1, 1, 2]
+  - label: degree
+    distribution: IntUniform
+    lower: 2
+    upper: 5
+    condition: {parent: kernel, values: [1]}
+constraints:
+  - x < 2 * degree
+`,
+	}
+
+	for name, content := range files {
+		file := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		restrictions, constraints, probabilities, err := generators.LoadSpace(file)
+		if err != nil {
+			t.Fatal(name, err)
+		}
+		if len(restrictions) != 3 || len(constraints) != 1 || fmt.Sprint(probabilities) != "[0.5 0.5 0.5]" {
+			t.Error(name, "Unexpected search space", len(restrictions), len(constraints), probabilities)
+			continue
+		}
+
+		// the discrete values stay integers
+		for _, choice := range restrictions[1].Choices {
+			if _, ok := choice.Value.(int); !ok {
+				t.Errorf("%s: the discrete value %v should be an int, not %T", name, choice.Value, choice.Value)
+			}
+		}
+
+		condition := restrictions[2].Condition
+		if condition == nil || condition.Parent != "kernel" || len(condition.Values) != 1 || condition.Values[0] != 1 {
+			t.Error(name, "The degree should be conditional on the polynomial kernel", condition)
+		}
+
+		point := functions.MultidimensionalPoint{Values: map[string]interface{}{"x": 3.0, "kernel": 1, "degree": 2}}
+		if !generators.Feasible(point, constraints) {
+			t.Error(name, "The constraint should hold", point.Values)
+		}
+		point.Values["x"] = 4.5
+		if generators.Feasible(point, constraints) {
+			t.Error(name, "The constraint should not hold", point.Values)
+		}
+	}
+
+	uniform := func(label string) generators.DimensionSpec {
+		return generators.DimensionSpec{Label: label, Distribution: "Uniform", Lower: 0, Upper: 1}
+	}
+	child := uniform("y")
+	child.Condition = &generators.ConditionSpec{Parent: "x", Values: []interface{}{0.5}}
+	unknown := uniform("x")
+	unknown.Distribution = "Cauchy"
+
+	invalid := map[string]generators.SpaceSpec{
+		"unknown distribution": {Dimensions: []generators.DimensionSpec{unknown}},
+		"duplicate label":      {Dimensions: []generators.DimensionSpec{uniform("x"), uniform("x")}},
+		"parent after child":   {Dimensions: []generators.DimensionSpec{child, uniform("x")}},
+		"unknown constraint label": {Dimensions: []generators.DimensionSpec{uniform("x")},
+			Constraints: []string{"x < z"}},
+	}
+	for name, spec := range invalid {
+		if _, _, _, err := spec.Build(); err == nil {
+			t.Error("The search space should be rejected:", name)
+		}
+	}
+
+}
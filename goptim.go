@@ -7,6 +7,8 @@ import (
 	"github.com/acflorea/goptim/functions"
 	"github.com/acflorea/goptim/generators"
 	"github.com/bluele/slack"
+	"math"
+	"time"
 )
 
 // The result of one trial
@@ -29,10 +31,51 @@ func main() {
 	maxAttemptsPtr := flag.Int("maxAttempts", 300, "Maximum number of trials in an experiment")
 	fct := flag.String("fct", "F_identity", "Target function")
 	alg := flag.String("alg", "SeqSplit", "Parallel random generator strategy")
+	sampler := flag.String("sampler", "Random",
+		"Point generation strategy (Random, Sobol, Halton, LatinHypercube, Grid, GaussianProcess, TPE, "+
+			"CMAES, DE, GA, PSO, Annealing, NSGA2)")
+	adaptation := flag.String("adaptation", "Fixed",
+		"How the weighted random search sets the probabilities to change (Fixed, Importance, OneFifth, Bandit)")
+	gridResolution := flag.Int("gridResolution", generators.DefaultGridResolution,
+		"Number of values of the continuous dimensions in a grid search")
+	acquisition := flag.String("acquisition", "EI", "Acquisition function of the model based samplers (EI, UCB, PI)")
+	initialPoints := flag.Int("initialPoints", generators.DefaultInitialPoints,
+		"Number of random points evaluated before a model based sampler uses its model")
+	ipop := flag.Bool("ipop", true, "Double the CMA-ES population at each restart")
+	deStrategy := flag.String("deStrategy", "rand/1/bin", "Differential evolution strategy (rand/1/bin, best/1/bin)")
+	cooling := flag.String("cooling", "Exponential", "Simulated annealing schedule (Exponential, Linear, Logarithmic)")
+	temperature := flag.Float64("temperature", generators.DefaultTemperature, "Initial simulated annealing temperature")
+	refinement := flag.Float64("refinement", 0,
+		"Fraction of the remaining attempts used to refine the WRS optimum with Nelder-Mead (0 to disable)")
+	hyperband := flag.Bool("hyperband", false, "Evaluate points with Hyperband at partial budgets (budgeted functions only)")
+	minBudget := flag.Float64("minBudget", core.DefaultMinBudget, "Smallest fraction of the budget used by Hyperband")
+	eta := flag.Int("eta", core.DefaultEta, "Hyperband reduction factor, the best 1/eta points of a budget are kept")
+	stoppingRule := flag.String("stoppingRule", "Probabilistic",
+		"Rule that stops the search (Secretary, Probabilistic, KSecretary, Patience, Target)")
+	observation := flag.Float64("observation", 1/math.E, "Fraction of the attempts observed before the search may stop")
+	secretaryK := flag.Int("secretaryK", 1, "KSecretary stops at a point among the best secretaryK points seen so far")
+	patience := flag.Int("patience", core.DefaultPatience, "Patience stops after this many points without improvement")
+	target := flag.Float64("target", 0, "Target stops at the first point that reaches this value")
+	timeBudget := flag.Duration("timeBudget", 0, "Wall-clock time of an experiment, e.g. 2h30m (0 for no limit)")
+	costBudget := flag.Duration("costBudget", 0,
+		"Total evaluation time of the workers in an experiment (0 for no limit)")
+	checkpoint := flag.String("checkpoint", "", "File the state of the run is saved to")
+	checkpointInterval := flag.Duration("checkpointInterval", core.DefaultCheckpointInterval,
+		"How often the trials of the experiment in progress are saved")
+	resume := flag.Bool("resume", false, "Continue the run saved in the checkpoint file")
+	multiObjective := flag.Bool("multiObjective", false,
+		"Maximize all the objectives of the function and report the Pareto front (multi-objective functions only)")
+	pruner := flag.String("pruner", "", "Pruner of the trials that report intermediate values (Median, Percentile)")
+	pruningPercentile := flag.Float64("pruningPercentile", 25, "Percentile a trial has to reach to continue")
+	pruningWarmup := flag.Int("pruningWarmup", 0, "Number of steps before which no trial is pruned")
+	pruningMinTrials := flag.Int("pruningMinTrials", core.DefaultPruningMinTrials,
+		"Number of trials that should reach a step before the trials are pruned at that step")
 	script := flag.String("script", "", "External script to run")
 	command := flag.String("command", "", "External program to execute")
 	workers := flag.Int("w", 8, "Number of goroutines")
 	targetstop := flag.Int("targetstop", 0, "Target stop")
+	space := flag.String("space", "", "JSON or YAML file describing the search space")
+	seedPtr := flag.Int64("seed", 0, "Seed of the random generators (0 for a time based seed)")
 
 	useRandomSamplePtr := flag.Bool("useRandomSample", true, "Use a single random sample instead of whole target space")
 
@@ -46,10 +89,51 @@ func main() {
 	vargs["maxAttempts"] = *maxAttemptsPtr
 	vargs["fct"] = *fct
 	vargs["alg"] = *alg
+	vargs["sampler"] = *sampler
+	vargs["adaptation"] = *adaptation
+	vargs["gridResolution"] = *gridResolution
+	vargs["acquisition"] = *acquisition
+	vargs["initialPoints"] = *initialPoints
+	vargs["ipop"] = *ipop
+	vargs["deStrategy"] = *deStrategy
+	vargs["cooling"] = *cooling
+	vargs["temperature"] = *temperature
+	vargs["refinement"] = *refinement
+	vargs["hyperband"] = *hyperband
+	vargs["minBudget"] = *minBudget
+	vargs["eta"] = *eta
+	vargs["stoppingRule"] = *stoppingRule
+	vargs["observation"] = *observation
+	vargs["secretaryK"] = *secretaryK
+	vargs["patience"] = *patience
+	// without -target the Target rule has no value to stop at
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "target" {
+			vargs["target"] = *target
+		}
+	})
+	vargs["timeBudget"] = *timeBudget
+	vargs["costBudget"] = *costBudget
+	vargs["checkpoint"] = *checkpoint
+	vargs["checkpointInterval"] = *checkpointInterval
+	vargs["resume"] = *resume
+	vargs["multiObjective"] = *multiObjective
+	vargs["pruner"] = *pruner
+	vargs["pruningPercentile"] = *pruningPercentile
+	vargs["pruningWarmup"] = *pruningWarmup
+	vargs["pruningMinTrials"] = *pruningMinTrials
 	vargs["script"] = *script
 	vargs["command"] = *command
 	vargs["workers"] = *workers
 	vargs["targetstop"] = *targetstop
+	vargs["space"] = *space
+
+	// The seed is always reported (in the vargs) so that the run can be reproduced
+	seed := *seedPtr
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	vargs["seed"] = seed
 
 	vargs["adjustSingleValue"] = false
 	vargs["optimalSlicePercent"] = 100.0
@@ -84,9 +168,36 @@ func optimize_k7m(vargs map[string]interface{}) {
 	// The function we attempt to optimize
 	targetFunction := functions.Functions[vargs["fct"].(string)]
 
+	// The multi-objective version of the function returns all its objectives
+	if vargs["multiObjective"].(bool) {
+		multiObjectiveFunction, ok := functions.MultiObjectiveFunctions[vargs["fct"].(string)]
+		if !ok {
+			panic(fmt.Errorf("%s has a single objective", vargs["fct"]))
+		}
+		vargs["multiObjectiveFunction"] = multiObjectiveFunction
+	}
+
+	// Hyperband needs a version of the function that accepts a budget
+	if vargs["hyperband"].(bool) {
+		budgetedFunction, ok := functions.BudgetedFunctions[vargs["fct"].(string)]
+		if !ok {
+			panic(fmt.Errorf("%s does not accept a budget, it cannot be used with Hyperband", vargs["fct"]))
+		}
+		vargs["budgetedFunction"] = budgetedFunction
+	}
+
 	// Algorithm
 	//(generators.SeqSplit seems to rule)
-	algorithm := generators.Algorithms[vargs["alg"].(string)]
+	algorithm, ok := generators.Algorithms[vargs["alg"].(string)]
+	if !ok {
+		panic(fmt.Errorf("unknown algorithm %s", vargs["alg"]))
+	}
+
+	// Sampler (the default one is the weighted random search)
+	sampler, ok := generators.Samplers[vargs["sampler"].(string)]
+	if !ok {
+		panic(fmt.Errorf("unknown sampler %s", vargs["sampler"]))
+	}
 
 	// number of workers
 	W := vargs["workers"].(int)
@@ -103,51 +214,83 @@ func optimize_k7m(vargs map[string]interface{}) {
 
 	optimalSlicePercent := vargs["optimalSlicePercent"].(float64)
 
+	var restrictions []generators.GenerationStrategy
+	var constraints []generators.Constraint
+	var probabilityToChange []float64
+
+	if spaceFile := vargs["space"].(string); spaceFile != "" {
+		// The search space is described in a file
+		var err error
+		restrictions, constraints, probabilityToChange, err = generators.LoadSpace(spaceFile)
+		if err != nil {
+			panic(err)
+		}
+	} else {
+		restrictions, constraints, probabilityToChange = k7mSpace()
+	}
+
+	seed := vargs["seed"].(int64)
+
+	useRandomSample := vargs["useRandomSample"].(bool)
+	if useRandomSample {
+		restrictions = append(restrictions, generators.NewUniform("seed", 0, 10000000))
+	}
+
+	core.Optimize(
+		noOfExperiments,
+		restrictions,
+		constraints,
+		probabilityToChange,
+		adjustSingleValue,
+		optimalSlicePercent,
+		maxAttempts,
+		targetstop,
+		W,
+		algorithm,
+		sampler,
+		seed,
+		targetFunction,
+		silent,
+		vargs)
+
+}
+
+// The default K7M search space
+func k7mSpace() ([]generators.GenerationStrategy, []generators.Constraint, []float64) {
+
 	// Generators
 
 	//# 1 to 55 increment of 1
 	//max_breadth = int(getValue(argumentsDict, '-w', '--max_breadth', 1))
-	max_breadth_map := make(map[interface{}]float64)
-	for i := 1; i <= 55; i++ {
-		max_breadth_map[i] = 1.0
-	}
-	max_breadth := generators.NewDiscrete("max_breadth", max_breadth_map)
+	max_breadth := generators.NewIntUniform("max_breadth", 1, 55)
 
 	//# 2 to 55 increment of 1
 	//max_depth = int(getValue(argumentsDict, '-d', '--max_depth', 2))
-	max_depth_map := make(map[interface{}]float64)
-	for i := 2; i <= 55; i++ {
-		max_depth_map[i] = 1.0
-	}
-	max_depth := generators.NewDiscrete("max_depth", max_depth_map)
+	max_depth := generators.NewIntUniform("max_depth", 2, 55)
 
 	//# -50 to 50 increment of 0.1
 	//attr_b = float(getValue(argumentsDict, '-b', '--attr_b', -50))
-	attr_b := generators.NewUniform("attr_b", -50, 50)
+	attr_b := generators.NewQUniform("attr_b", -50, 50, 0.1)
 
 	//# -1 to 1 increment of 0.1
 	//attr_c = float(getValue(argumentsDict, '-c', '--attr_c', -1))
-	attr_c := generators.NewUniform("attr_c", -1, 1)
+	attr_c := generators.NewQUniform("attr_c", -1, 1, 0.1)
 
 	//# 0.1 to 1 increment of 0.1
 	//edge_cost = float(getValue(argumentsDict, '-e', '--edge_cost', 0.1))
-	edge_cost := generators.NewUniform("edge_cost", 0.1, 1)
+	edge_cost := generators.NewQUniform("edge_cost", 0.1, 1, 0.1)
 
 	//# 1 to 55 increment of 1
 	//movement_factor = int(getValue(argumentsDict, '-m', '--movement_factor', 1))
-	movement_factor_map := make(map[interface{}]float64)
-	for i := 1; i <= 55; i++ {
-		movement_factor_map[i] = 1.0
-	}
-	movement_factor := generators.NewDiscrete("movement_factor", movement_factor_map)
+	movement_factor := generators.NewIntUniform("movement_factor", 1, 55)
 
 	restrictions := []generators.GenerationStrategy{max_breadth, max_depth, attr_b, attr_c, edge_cost, movement_factor}
 
-	useRandomSample := vargs["useRandomSample"].(bool)
-	if useRandomSample {
-		seed := 123456
-		vargs["seed"] = seed
-		restrictions = append(restrictions, generators.NewUniform("seed", 0, 10000000))
+	// the tree is at least as deep as it is wide
+	constraints := []generators.Constraint{
+		func(p functions.MultidimensionalPoint) bool {
+			return p.Values["max_depth"].(int) >= p.Values["max_breadth"].(int)
+		},
 	}
 
 	//Sum of fractions for main effects 54.29%
@@ -174,7 +317,7 @@ func optimize_k7m(vargs map[string]interface{}) {
 	//10.59% due to main effect: X2
 	//15.06% due to main effect: X1
 
-	// fANOVA - list them here for brevity...
+	// fANOVA (-adaptation Importance estimates them during the run) - list them here for brevity...
 	// attr_c
 	var x1 = 15.06
 	// edge_cost
@@ -193,18 +336,5 @@ func optimize_k7m(vargs map[string]interface{}) {
 	// ...
 	var probabilityToChange = []float64{x0, x1, x2, x3, x4, x5}
 
-	core.Optimize(
-		noOfExperiments,
-		restrictions,
-		probabilityToChange,
-		adjustSingleValue,
-		optimalSlicePercent,
-		maxAttempts,
-		targetstop,
-		W,
-		algorithm,
-		targetFunction,
-		silent,
-		vargs)
-
+	return restrictions, constraints, probabilityToChange
 }